@@ -1,41 +1,426 @@
 package main
 
 import (
-	"fmt"
+	"crypto/rand"
 	"http-url-shortener/internal/handlers"
+	"http-url-shortener/internal/middleware"
+	"http-url-shortener/internal/repositories/apikeyfilesystemrepository"
+	"http-url-shortener/internal/repositories/instrumentedrepository"
+	"http-url-shortener/internal/repositories/repositoryinterface"
 	"http-url-shortener/internal/repositories/shortenedurlfilesystemrepository"
-	"log"
+	"http-url-shortener/internal/services/configservice"
+	"http-url-shortener/internal/services/domainpolicyservice"
+	"http-url-shortener/internal/services/geoipservice"
+	"http-url-shortener/internal/services/jwtservice"
+	"http-url-shortener/internal/services/logservice"
+	"http-url-shortener/internal/services/metricsservice"
+	"http-url-shortener/internal/services/passwordservice"
+	"http-url-shortener/internal/services/ratelimitservice"
+	"http-url-shortener/internal/services/responseservice"
+	"http-url-shortener/internal/services/shortcodeservice"
+	"http-url-shortener/internal/services/tlsservice"
+	"http-url-shortener/internal/services/urlcheckservice"
+	"http-url-shortener/internal/services/urlpolicyservice"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// options determines the behaviour of the API's handlers, and is populated from config on startup
+var options = handlers.Options{}
+
 func main() {
-	http.HandleFunc("/", apiHandler)
+	config := configservice.Load()
+
+	options = handlers.Options{
+		AuthRequired: config.AuthRequired,
+		LinkQuota:    config.LinkQuota,
+		URLPolicy: urlpolicyservice.Policy{
+			AllowedSchemes:      config.URLSchemes,
+			MaxLength:           config.URLMaxLength,
+			StripTrackingParams: config.StripTracking,
+		},
+		SelfHosts:         config.SelfHosts,
+		ResolveSelfLinks:  config.ResolveSelfLinks,
+		QuarantineThreats: config.QuarantineThreats,
+		ParamConflict:     config.ParamConflict,
+		ShortCodes: shortcodeservice.Canonicaliser{
+			CaseInsensitive: config.CaseInsensitive,
+			Confusables:     config.Confusables,
+		},
+		TrustProxy:        config.TrustProxy,
+		InactiveURL:       config.InactiveURL,
+		NotFoundURL:       config.NotFoundURL,
+		NotFoundSearchURL: config.NotFoundSearchURL,
+		SiteName:          config.SiteName,
+		RedirectMaxAge:    config.RedirectMaxAge,
+		Unlocker: passwordservice.Unlocker{
+			Key: newUnlockKey(config),
+			TTL: config.UnlockTTL,
+		},
+	}
+
+	if config.UnlockRateLimit > 0 {
+		options.UnlockLimiter = ratelimitservice.New(config.UnlockRateLimit, config.UnlockBurst)
+	}
+
+	logger := logservice.New(os.Stdout, config.LogLevel)
+	slog.SetDefault(logger)
+
+	// migrate existing short codes to canonical form
+	migrated, conflicts, err := options.ShortCodes.Migrate(newRepository(logger))
+	if err != nil {
+		fatal(logger, err)
+	}
+
+	if migrated > 0 || len(conflicts) > 0 {
+		logger.Info("Migrated short codes to canonical form", "migrated", migrated, "conflicts", conflicts)
+	}
+
+	domainPolicy, err := newDomainPolicy(config)
+	if err != nil {
+		fatal(logger, err)
+	}
+	options.DomainPolicy = domainPolicy
+
+	if config.GeoIPDatabase != "" {
+		geoIP, err := geoipservice.NewDatabase(config.GeoIPDatabase)
+		if err != nil {
+			fatal(logger, err)
+		}
+		options.GeoIP = geoIP
+	}
+
+	if config.ThreatList != "" {
+		threats, err := urlcheckservice.NewHashPrefixList(config.ThreatList)
+		if err != nil {
+			fatal(logger, err)
+		}
+		options.URLChecker = threats
+
+		if config.ThreatRescan > 0 {
+			go rescanThreats(logger, threats, config.ThreatRescan)
+		}
+	}
+
+	// expose number of links as a gauge
+	metricsservice.DefaultRegistry.NewGaugeFunc(
+		"shortener_links",
+		"Number of shortened URLs held by the repository.",
+		func() float64 {
+			count, _ := newRepository(logger).Count("")
+			return float64(count)
+		},
+	)
+
+	var handler http.Handler = http.HandlerFunc(apiHandler)
+
+	if config.AuthRequired {
+		handler = middleware.RequireScope(handler)
+	}
+
+	// rate limit each client, once authenticated
+	handler = middleware.RateLimit(newRateLimiter(config), config.TrustProxy, handler)
+
+	// authenticate API keys and, if configured, bearer tokens
+	handler = middleware.APIKeyAuth(newAPIKeyRepository(), handler)
+
+	if config.JWTJWKS != "" {
+		validator := jwtservice.New(
+			jwtservice.NewKeySet(config.JWTJWKS, config.JWTJWKSRefresh),
+			config.JWTIssuer,
+			config.JWTAudience,
+			config.JWTRolesClaim,
+		)
+		handler = middleware.BearerAuth(validator, handler)
+	}
+
+	// rate limit attempts to authenticate each client, whether or not they succeed
+	handler = middleware.RateLimit(newAuthRateLimiter(config), config.TrustProxy, handler)
+
+	handler = middleware.RequestID(logger, middleware.AccessLog(
+		middleware.Metrics(routeName, middleware.Recover(handler)),
+	))
+
+	if !config.TLSEnabled() {
+		logger.Info("Listening", "addr", config.HTTPAddr)
+		fatal(logger, http.ListenAndServe(config.HTTPAddr, handler))
+	}
+
+	reloader, err := tlsservice.New(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		fatal(logger, err)
+	}
+
+	// reload certificate on file change or SIGHUP
+	go reloader.Watch(config.TLSReloadInterval, nil, func(err error) {
+		logger.Error("Failed to reload certificate", "error", err)
+	})
+	go reloadOnSignal(logger, reloader)
 
-	fmt.Println("Listening on port 8080...")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	// redirect plain HTTP requests to HTTPS, except for probes
+	go func() {
+		logger.Info("Redirecting to HTTPS", "addr", config.HTTPAddr)
+		fatal(logger, http.ListenAndServe(config.HTTPAddr, middleware.RedirectToHTTPS(config.HTTPSAddr, handler)))
+	}()
+
+	server := &http.Server{
+		Addr:      config.HTTPSAddr,
+		Handler:   middleware.HSTS(config.HSTSMaxAge, handler),
+		TLSConfig: reloader.TLSConfig(),
+	}
+
+	logger.Info("Listening", "addr", config.HTTPSAddr, "tls", true)
+	fatal(logger, server.ListenAndServeTLS("", ""))
 }
 
 func apiHandler(w http.ResponseWriter, r *http.Request) {
-	workdir, _ := os.Getwd()
-	repository := shortenedurlfilesystemrepository.New(workdir + "/data")
+	repository := newRepository(logservice.FromContext(r.Context()))
+
+	// probe endpoints
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/version", "/metrics":
+		if r.Method != "GET" {
+			write(w, r, methodNotAllowed)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/healthz":
+		write(w, r, handlers.GetHealth(w, r))
+		return
+	case "/readyz":
+		write(w, r, handlers.GetReady(repository, w, r))
+		return
+	case "/version":
+		write(w, r, handlers.GetVersion(repository, w, r))
+		return
+	case "/metrics":
+		metricsservice.DefaultRegistry.ServeHTTP(w, r)
+		return
+	}
 
 	// shortener endpoint
 	if r.URL.Path == "/api/shorten" {
 		if r.Method != "POST" {
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			write(w, r, methodNotAllowed)
+			return
+		}
+
+		write(w, r, handlers.PostShorten(repository, options, w, r))
+		return
+	}
+
+	// link management endpoints
+	if r.URL.Path == "/api/links" {
+		if r.Method != "GET" {
+			write(w, r, methodNotAllowed)
+			return
+		}
+
+		write(w, r, handlers.GetLinks(repository, options, w, r))
+		return
+	}
+
+	if r.URL.Path == "/api/campaigns" {
+		if r.Method != "GET" {
+			write(w, r, methodNotAllowed)
 			return
 		}
 
-		handlers.PostShorten(repository, w, r).Write(w)
+		write(w, r, handlers.GetCampaigns(repository, options, w, r))
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/links/") {
+		switch r.Method {
+		case "PUT":
+			write(w, r, handlers.PutLink(repository, options, w, r))
+		case "DELETE":
+			write(w, r, handlers.DeleteLink(repository, options, w, r))
+		default:
+			write(w, r, methodNotAllowed)
+		}
+
+		return
+	}
+
+	// try to redirect a short URL, or preview where it goes, unlocking it first if it is protected by a password
+	if r.Method == "POST" {
+		write(w, r, handlers.PostShortURLUnlock(repository, options, w, r))
 		return
 	}
 
-	// try to redirect a short URL
 	if r.Method != "GET" {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		write(w, r, methodNotAllowed)
 		return
 	}
 
-	handlers.GetShortURLRedirect(repository, w, r).Write(w)
+	if isPreview(r) {
+		write(w, r, handlers.GetShortURLPreview(repository, options, w, r))
+		return
+	}
+
+	write(w, r, handlers.GetShortURLRedirect(repository, options, w, r))
+}
+
+// methodNotAllowed is the response to a request whose method is not supported by its path
+var methodNotAllowed = responseservice.NewErrResponse("Method not allowed", http.StatusMethodNotAllowed)
+
+// write sends a handler's response in the format that the client accepts, referencing the request ID in any error payload
+func write(w http.ResponseWriter, r *http.Request, resp responseservice.JSONResponse) {
+	resp.WithRequestID(logservice.RequestID(r.Context())).WriteNegotiated(w, r)
+}
+
+func newDomainPolicy(config configservice.Config) (domainpolicyservice.Policy, error) {
+	allow, err := domainpolicyservice.NewList(config.DomainAllowlist)
+	if err != nil {
+		return domainpolicyservice.Policy{}, err
+	}
+
+	block, err := domainpolicyservice.NewList(config.DomainBlocklist)
+	if err != nil {
+		return domainpolicyservice.Policy{}, err
+	}
+
+	return domainpolicyservice.Policy{Allow: allow, Block: block}, nil
+}
+
+// newUnlockKey returns the key that signs the cookies of unlocked links, which is random unless configured so that
+// the cookies remain valid across restarts and instances
+func newUnlockKey(config configservice.Config) []byte {
+	if config.UnlockSecret != "" {
+		return []byte(config.UnlockSecret)
+	}
+
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	return key
+}
+
+func newRepository(logger *slog.Logger) repositoryinterface.RepositoryInterface {
+	return instrumentedrepository.New(shortenedurlfilesystemrepository.New(dataDir()).WithLogger(logger))
+}
+
+func newAPIKeyRepository() repositoryinterface.APIKeyRepositoryInterface {
+	return apikeyfilesystemrepository.New(dataDir())
+}
+
+func dataDir() string {
+	workdir, _ := os.Getwd()
+	return workdir + "/data"
+}
+
+// newRateLimiter returns a function that selects the rate limit for a request, with separate limits for shorten and
+// redirect (including preview), and a default limit for every other route but the health probes
+func newRateLimiter(config configservice.Config) func(*http.Request) *ratelimitservice.Limiter {
+	var shorten, redirect, other *ratelimitservice.Limiter
+
+	if config.ShortenRateLimit > 0 {
+		shorten = ratelimitservice.New(config.ShortenRateLimit, config.ShortenBurst)
+	}
+
+	if config.RedirectRateLimit > 0 {
+		redirect = ratelimitservice.New(config.RedirectRateLimit, config.RedirectBurst)
+	}
+
+	if config.APIRateLimit > 0 {
+		other = ratelimitservice.New(config.APIRateLimit, config.APIBurst)
+	}
+
+	return func(r *http.Request) *ratelimitservice.Limiter {
+		switch routeName(r) {
+		case "/api/shorten":
+			return shorten
+		case "/{code}", "/{code}+":
+			return redirect
+		case "/healthz", "/readyz":
+			return nil
+		}
+
+		return other
+	}
+}
+
+// newAuthRateLimiter returns a function that selects the rate limit for a request bearing credentials, which is applied
+// by IP address before the credentials are authenticated, so that they can't be guessed without limit
+func newAuthRateLimiter(config configservice.Config) func(*http.Request) *ratelimitservice.Limiter {
+	var auth *ratelimitservice.Limiter
+
+	if config.AuthRateLimit > 0 {
+		auth = ratelimitservice.New(config.AuthRateLimit, config.AuthBurst)
+	}
+
+	return func(r *http.Request) *ratelimitservice.Limiter {
+		if r.Header.Get("X-API-Key") == "" && r.Header.Get("Authorization") == "" {
+			return nil
+		}
+
+		return auth
+	}
+}
+
+// routeName returns the route that a request was handled by, for use as a metric label
+func routeName(r *http.Request) string {
+	switch r.URL.Path {
+	case "/", "/api/shorten", "/api/links", "/api/campaigns", "/healthz", "/readyz", "/version", "/metrics":
+		return r.URL.Path
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/links/") {
+		return "/api/links/{code}"
+	}
+
+	if isPreview(r) {
+		return "/{code}+"
+	}
+
+	return "/{code}"
+}
+
+// isPreview determines whether a request is for a preview of a short URL, rather than to be redirected
+func isPreview(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, "+") || r.URL.Query().Has("preview")
+}
+
+// rescanThreats periodically reloads the threat list, and re-checks every existing link against it
+func rescanThreats(logger *slog.Logger, threats *urlcheckservice.HashPrefixList, interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := threats.Reload(); err != nil {
+			logger.Error("Failed to reload threat list", "error", err)
+		}
+
+		changed, err := urlcheckservice.Rescan(newRepository(logger), threats, logger)
+		if err != nil {
+			logger.Error("Failed to rescan links", "error", err)
+			continue
+		}
+
+		logger.Info("Rescanned links", "changed", changed)
+	}
+}
+
+func reloadOnSignal(logger *slog.Logger, reloader *tlsservice.CertificateReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := reloader.Reload(); err != nil {
+			logger.Error("Failed to reload certificate", "error", err)
+			continue
+		}
+
+		logger.Info("Reloaded certificate")
+	}
+}
+
+func fatal(logger *slog.Logger, err error) {
+	logger.Error("Server stopped", "error", err)
+	os.Exit(1)
 }
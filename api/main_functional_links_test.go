@@ -0,0 +1,519 @@
+package main
+
+import (
+	"fmt"
+	"http-url-shortener/internal/handlers"
+	"http-url-shortener/internal/services/authservice"
+	"http-url-shortener/internal/services/responseservice"
+	"http-url-shortener/internal/services/urlcheckservice"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestItListsOnlyThePrincipalsOwnLinks(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}, "DEF2": {"long": "http://wikipedia.org", "owner": "key2"}}`)
+	defer useOptions(handlers.Options{AuthRequired: true})()
+
+	r := newPrincipalRequest("GET", "/api/links", "", authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	links := json["data"].(map[string]interface{})["links"].([]interface{})
+	if len(links) != 1 || links[0].(map[string]interface{})["shortCode"] != "ABC1" {
+		t.Error(fmt.Sprintf("Expected only link 'ABC1', instead received '%+v'", links))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItListsEveryLinkForAnAdmin(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}, "DEF2": {"long": "http://wikipedia.org", "owner": "key2"}}`)
+	defer useOptions(handlers.Options{AuthRequired: true})()
+
+	r := newPrincipalRequest("GET", "/api/links", "", authservice.Principal{ID: "key3", Scopes: []string{"admin"}})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+
+	json := responseservice.ParseJSON(w.Result())
+
+	links := json["data"].(map[string]interface{})["links"].([]interface{})
+	if len(links) != 2 {
+		t.Error(fmt.Sprintf("Expected 2 links, instead received '%+v'", links))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItReturnsUnauthorizedWhenListingLinksAnonymously(t *testing.T) {
+	defer useOptions(handlers.Options{AuthRequired: true})()
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/api/links", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusUnauthorized, resp.StatusCode))
+	}
+}
+
+func TestItFiltersLinksByCampaign(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "campaign": "spring"}, "DEF2": {"long": "http://wikipedia.org"}}`)
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/api/links?campaign=spring", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+
+	json := responseservice.ParseJSON(w.Result())
+
+	links := json["data"].(map[string]interface{})["links"].([]interface{})
+	if len(links) != 1 || links[0].(map[string]interface{})["shortCode"] != "ABC1" {
+		t.Error(fmt.Sprintf("Expected only link 'ABC1', instead received '%+v'", links))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItGroupsThePrincipalsLinksByCampaign(t *testing.T) {
+	// set expected data
+	setTestData(`{
+		"ABC1": {"long": "http://bbc.co.uk", "owner": "key1", "campaign": "spring", "clicks": 3},
+		"DEF2": {"long": "http://wikipedia.org", "owner": "key1", "campaign": "spring", "clicks": 2},
+		"GHJ3": {"long": "http://example.com", "owner": "key1", "clicks": 1},
+		"KMN4": {"long": "http://example.org", "owner": "key2", "campaign": "spring", "clicks": 7}
+	}`)
+	defer useOptions(handlers.Options{AuthRequired: true})()
+
+	r := newPrincipalRequest("GET", "/api/campaigns", "", authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	campaigns := json["data"].(map[string]interface{})["campaigns"].([]interface{})
+	expected := `[map[campaign: clicks:1 links:1] map[campaign:spring clicks:5 links:2]]`
+	if fmt.Sprintf("%v", campaigns) != expected {
+		t.Error(fmt.Sprintf("Expected campaigns '%s', instead received '%v'", expected, campaigns))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItUpdatesALinkOwnedByThePrincipal(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}}`)
+	defer useOptions(handlers.Options{AuthRequired: true})()
+
+	r := newPrincipalRequest("PUT", "/api/links/ABC1", `{"url": "http://wikipedia.org"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	jsonData := responseservice.ParseJSON(resp)["data"].(map[string]interface{})
+	if jsonData["url"] != "http://wikipedia.org" {
+		t.Error(fmt.Sprintf("Expected url of 'http://wikipedia.org', instead received '%s'", jsonData["url"]))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItKeepsTheActiveWindowWhenUpdatingALink(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "activeFrom": 1767225600}}`)
+
+	tests := map[string]string{
+		`{"url": "http://bbc.co.uk"}`:                                            "2026-01-01T00:00:00Z",
+		`{"url": "http://bbc.co.uk", "activeFrom": "2026-03-01T09:00:00+01:00"}`: "2026-03-01T08:00:00Z",
+		`{"url": "http://bbc.co.uk", "activeFrom": ""}`:                          "<nil>",
+	}
+
+	for body, expected := range tests {
+		r := httptest.NewRequest("PUT", "http://localhost:8080/api/links/ABC1", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+
+		jsonData := responseservice.ParseJSON(w.Result())["data"].(map[string]interface{})
+		if fmt.Sprint(jsonData["activeFrom"]) != expected {
+			t.Error(fmt.Sprintf("Expected active from '%s' after '%s', instead received '%v'", expected, body, jsonData["activeFrom"]))
+		}
+
+		setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "activeFrom": 1767225600}}`)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItKeepsTheClicksOfVariantsWhenUpdatingALink(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "variants": [{"name": "a", "url": "http://bbc.co.uk/a", "weight": 1, "clicks": 4}]}}`)
+
+	r := httptest.NewRequest(
+		"PUT",
+		"http://localhost:8080/api/links/ABC1",
+		strings.NewReader(`{"url": "http://bbc.co.uk", "variants": [{"url": "http://bbc.co.uk/a", "weight": 70}, {"url": "http://bbc.co.uk/b", "weight": 30}]}`),
+	)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+
+	jsonData := responseservice.ParseJSON(w.Result())["data"].(map[string]interface{})
+
+	variants := fmt.Sprintf("%v", jsonData["variants"])
+	expected := "[map[clicks:4 name:a url:http://bbc.co.uk/a weight:70] map[clicks:0 name:b url:http://bbc.co.uk/b weight:30]]"
+	if variants != expected {
+		t.Error(fmt.Sprintf("Expected variants '%s', instead received '%s'", expected, variants))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItDoesNotUpdateALinkOwnedByAnotherPrincipal(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}}`)
+	defer useOptions(handlers.Options{AuthRequired: true})()
+
+	r := newPrincipalRequest("PUT", "/api/links/ABC1", `{"url": "http://wikipedia.org"}`, authservice.Principal{ID: "key2"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusNotFound, resp.StatusCode))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItDeletesALinkAsAnAdmin(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}}`)
+	defer useOptions(handlers.Options{AuthRequired: true})()
+
+	r := newPrincipalRequest("DELETE", "/api/links/ABC1", "", authservice.Principal{ID: "key3", Scopes: []string{"admin"}})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusNoContent, resp.StatusCode))
+	}
+
+	// redirect should no longer resolve
+	w = httptest.NewRecorder()
+	apiHandler(w, httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusNotFound, w.Code))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRejectsShorteningWhenQuotaIsReached(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}}`)
+	defer useOptions(handlers.Options{AuthRequired: true, LinkQuota: 1})()
+
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://wikipedia.org"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusForbidden, resp.StatusCode))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRejectsShorteningADestinationNotOnTheAllowlist(t *testing.T) {
+	defer useOptions(handlers.Options{DomainPolicy: newTestDomainPolicy([]string{"*.co.uk"}, nil)})()
+
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://wikipedia.org"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusForbidden, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["message"] != "Destination host `wikipedia.org` is not on the allowlist" {
+		t.Error(fmt.Sprintf("Expected message explaining block, instead received '%s'", jsonData["message"]))
+	}
+
+	if jsonData["code"] != "blocked_domain" {
+		t.Error(fmt.Sprintf("Expected code of 'blocked_domain', instead received '%s'", jsonData["code"]))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRejectsShorteningALinkToTheShortenerItself(t *testing.T) {
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://LOCALHOST:8080/ABC1"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusBadRequest, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["message"] != "Destination must not link to this shortener" {
+		t.Error(fmt.Sprintf("Expected message of 'Destination must not link to this shortener', instead received '%s'", jsonData["message"]))
+	}
+}
+
+func TestItResolvesALinkToTheShortenerToItsFinalDestination(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "https://sho.rt/DEF2", "owner": "key1"}, "DEF2": {"long": "http://bbc.co.uk", "owner": "key1"}}`)
+	defer useOptions(handlers.Options{SelfHosts: []string{"sho.rt"}, ResolveSelfLinks: true})()
+
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://localhost:8080/ABC1"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	json := responseservice.ParseJSON(resp)
+
+	// the resolved destination was already shortened by the principal
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["shortURL"] != "http://localhost:8080/DEF2" {
+		t.Error(fmt.Sprintf("Expected shortURL of '%s', instead received '%s'", "http://localhost:8080/DEF2", jsonData["shortURL"]))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRejectsResolvingALinkToAnotherPrincipalsProtectedLink(t *testing.T) {
+	// set expected data
+	setTestData(`{"SEC1": {"long": "https://example.com/secret", "owner": "key2", "passwordHash": "hash", "maxClicks": 1}}`)
+	defer useOptions(handlers.Options{ResolveSelfLinks: true})()
+
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://localhost:8080/SEC1"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusBadRequest, resp.StatusCode))
+	}
+
+	r = newPrincipalRequest("GET", "/api/links", "", authservice.Principal{ID: "key1"})
+	w = httptest.NewRecorder()
+
+	apiHandler(w, r)
+
+	json := responseservice.ParseJSON(w.Result())
+
+	// the protected destination was never revealed to the principal
+	links := json["data"].(map[string]interface{})["links"].([]interface{})
+	if len(links) != 0 {
+		t.Error(fmt.Sprintf("Expected no links, instead received '%+v'", links))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRedirectsViaAProtectedLinkToTheShortener(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://localhost:8080/SEC1"}, "SEC1": {"long": "https://example.com/secret", "passwordHash": "hash"}}`)
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	location := resp.Header.Get("Location")
+	if location != "http://localhost:8080/SEC1" {
+		t.Error(fmt.Sprintf("Expected location of '%s', instead received '%s'", "http://localhost:8080/SEC1", location))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItReturnsLoopDetectedWhenRedirectingALinkThatLoops(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://localhost:8080/DEF2"}, "DEF2": {"long": "https://sho.rt/ABC1"}}`)
+	defer useOptions(handlers.Options{SelfHosts: []string{"sho.rt"}})()
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusLoopDetected {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusLoopDetected, resp.StatusCode))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSavesTheTitleOfANewLink(t *testing.T) {
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://bbc.co.uk", "title": " BBC ", "interstitial": true}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+
+	r = newPrincipalRequest("GET", "/api/links", "", authservice.Principal{ID: "key1"})
+	w = httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	json := responseservice.ParseJSON(resp)
+
+	link := json["data"].(map[string]interface{})["links"].([]interface{})[0].(map[string]interface{})
+	if link["title"] != "BBC" || link["interstitial"] != true {
+		t.Error(fmt.Sprintf("Expected titled link with interstitial, instead received '%+v'", link))
+	}
+
+	if _, ok := link["createdAt"]; !ok {
+		t.Error(fmt.Sprintf("Expected link to have a creation date, instead received '%+v'", link))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRejectsShorteningADestinationFlaggedAsAThreat(t *testing.T) {
+	defer useOptions(handlers.Options{
+		URLChecker: urlcheckservice.Mock{Threats: map[string]string{"http://evil.example": "phishing"}},
+	})()
+
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://evil.example/"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusForbidden, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["message"] != "Destination has been flagged as phishing" {
+		t.Error(fmt.Sprintf("Expected message of 'Destination has been flagged as phishing', instead received '%s'", jsonData["message"]))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItQuarantinesADestinationFlaggedAsAThreat(t *testing.T) {
+	defer useOptions(handlers.Options{
+		URLChecker:        urlcheckservice.Mock{Threats: map[string]string{"http://evil.example": "phishing"}},
+		QuarantineThreats: true,
+	})()
+
+	r := newPrincipalRequest("POST", "/api/shorten", `{"url": "http://evil.example"}`, authservice.Principal{ID: "key1"})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	json := responseservice.ParseJSON(resp)
+
+	shortURL := json["data"].(map[string]interface{})["shortURL"].(string)
+
+	// following the link shows a warning, rather than redirecting
+	r = httptest.NewRequest("GET", shortURL, nil)
+	w = httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp = w.Result()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusForbidden, resp.StatusCode))
+	}
+
+	if resp.Header.Get("Location") != "" {
+		t.Error(fmt.Sprintf("Expected no location header, instead received '%s'", resp.Header.Get("Location")))
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "flagged as <strong>phishing</strong>") {
+		t.Error(fmt.Sprintf("Expected warning page, instead received '%s'", body))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func newPrincipalRequest(method string, path string, body string, p authservice.Principal) *http.Request {
+	r := httptest.NewRequest(method, "http://localhost:8080"+path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	return r.WithContext(authservice.WithPrincipal(r.Context(), p))
+}
+
+// useOptions overrides the handler options for the duration of a test, returning a func to restore them
+func useOptions(o handlers.Options) func() {
+	previous := options
+	options = o
+
+	return func() {
+		options = previous
+	}
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"fmt"
+	"http-url-shortener/internal/services/logservice"
 	"http-url-shortener/internal/services/responseservice"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -29,11 +31,116 @@ func TestItFailsToShortenAURLWhenPayloadIsEmpty(t *testing.T) {
 	}
 
 	jsonData := json["data"].(map[string]interface{})
-	if jsonData["message"] != "unexpected end of JSON input" {
-		t.Error(fmt.Sprintf("Expected message of 'unexpected end of JSON input', instead received '%s'", jsonData["message"]))
+	if jsonData["message"] != "Request body is not a valid JSON object" {
+		t.Error(fmt.Sprintf("Expected message of 'Request body is not a valid JSON object', instead received '%s'", jsonData["message"]))
+	}
+
+	if jsonData["code"] != "invalid_json" {
+		t.Error(fmt.Sprintf("Expected code of 'invalid_json', instead received '%s'", jsonData["code"]))
 	}
 }
 
+func TestItDetailsTheFieldThatFailedValidationWhenFailingToShortenAURL(t *testing.T) {
+	tests := map[string][]string{
+		`{"url": "http://bbc.co.uk", "title": 1}`:                       {"invalid_request", "title"},
+		`{"url": "not a url"}`:                                          {"invalid_url", "url"},
+		`{"url": "http://bbc.co.uk", "variants": [{"url": "ftp://x"}]}`: {"invalid_url", "variants[0].url"},
+	}
+
+	for body, expected := range tests {
+		r := httptest.NewRequest("POST", "http://localhost:8080/api/shorten", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("Expected status code %d for '%s', instead received %d", http.StatusBadRequest, body, resp.StatusCode))
+		}
+
+		jsonData := responseservice.ParseJSON(resp)["data"].(map[string]interface{})
+		if jsonData["code"] != expected[0] {
+			t.Error(fmt.Sprintf("Expected code of '%s' for '%s', instead received '%s'", expected[0], body, jsonData["code"]))
+		}
+
+		errs, _ := jsonData["errors"].([]interface{})
+		if len(errs) != 1 || errs[0].(map[string]interface{})["field"] != expected[1] {
+			t.Error(fmt.Sprintf("Expected an error for field '%s' for '%s', instead received '%v'", expected[1], body, jsonData["errors"]))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItReturnsProblemDetailsWhenFailingToShortenAURL(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://localhost:8080/api/shorten", strings.NewReader(`{"url": `))
+	r.Header.Set("Accept", "application/problem+json")
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/problem+json" {
+		t.Error(fmt.Sprintf("Expected content type of 'application/problem+json', instead received '%s'", contentType))
+	}
+
+	problem := responseservice.ParseJSON(resp)
+
+	if problem["type"] != "urn:http-url-shortener:error:invalid_json" || problem["status"] != float64(http.StatusBadRequest) {
+		t.Error(fmt.Sprintf("Expected an invalid JSON problem, instead received '%v'", problem))
+	}
+
+	if problem["detail"] != "Request body is not a valid JSON object" {
+		t.Error(fmt.Sprintf("Expected detail of 'Request body is not a valid JSON object', instead received '%s'", problem["detail"]))
+	}
+}
+
+func TestItReferencesTheRequestIDWhenFailingToShortenAURL(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://localhost:8080/api/shorten", nil)
+	r = r.WithContext(logservice.WithRequestID(r.Context(), "abc123"))
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["requestId"] != "abc123" {
+		t.Error(fmt.Sprintf("Expected requestId of 'abc123', instead received '%s'", jsonData["requestId"]))
+	}
+}
+
+func TestItShortensAURLAsPlainText(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "http://bbc.co.uk"}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+		"Accept":       {"text/plain"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+		t.Error(fmt.Sprintf("Expected content type of 'text/plain; charset=utf-8', instead received '%s'", contentType))
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !regexp.MustCompile(`^http://localhost:8080/\w+\n$`).Match(body) {
+		t.Error(fmt.Sprintf("Expected just the short URL, instead received '%s'", body))
+	}
+
+	// clean up
+	clearTestData()
+}
+
 func TestItFailsToShortenAURLWhenLongURLIsMissingFromPayload(t *testing.T) {
 	w := httptest.NewRecorder()
 
@@ -213,6 +320,411 @@ func TestItReturnsAKnownShortURLWhenLongURLHasAlreadyBeenShortened(t *testing.T)
 	clearTestData()
 }
 
+func TestItFailsToShortenAURLWithADisallowedScheme(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "ftp://bbc.co.uk/file.txt"}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusBadRequest, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["message"] != "`url` scheme `ftp` is not allowed" {
+		t.Error(fmt.Sprintf("Expected message of '`url` scheme `ftp` is not allowed', instead received '%s'", jsonData["message"]))
+	}
+}
+
+func TestItReturnsAKnownShortURLWhenAnEquivalentLongURLHasAlreadyBeenShortened(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1":{"long":"http://bbc.co.uk"}}`)
+
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "HTTP://BBC.co.uk:80/"}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	expectedShortURL := "http://localhost:8080/ABC1"
+	if jsonData["shortURL"] != expectedShortURL {
+		t.Error(fmt.Sprintf("Expected shortURL of '%s', instead received '%s'", expectedShortURL, jsonData["shortURL"]))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItMergesUTMParametersIntoTheLongURLWhenShortening(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "http://bbc.co.uk/news?id=1&utm_source=old", "utm": {"source": "newsletter", "campaign": "spring"}}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	w = httptest.NewRecorder()
+	apiHandler(w, httptest.NewRequest("GET", "http://localhost:8080/api/links", nil))
+
+	json := responseservice.ParseJSON(w.Result())
+
+	links := json["data"].(map[string]interface{})["links"].([]interface{})
+	link := links[0].(map[string]interface{})
+
+	expectedLongURL := "http://bbc.co.uk/news?id=1&utm_source=newsletter&utm_campaign=spring"
+	if link["url"] != expectedLongURL {
+		t.Error(fmt.Sprintf("Expected long URL of '%s', instead received '%s'", expectedLongURL, link["url"]))
+	}
+
+	if link["campaign"] != "spring" {
+		t.Error(fmt.Sprintf("Expected campaign of '%s', instead received '%s'", "spring", link["campaign"]))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItFailsToShortenAURLWhenUTMParametersAreNotValid(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "http://bbc.co.uk", "utm": {"source": 123}}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusBadRequest, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	expectedMessage := "`utm.source` is a non-string"
+	if jsonData["message"] != expectedMessage {
+		t.Error(fmt.Sprintf("Expected message of '%s', instead received '%s'", expectedMessage, jsonData["message"]))
+	}
+}
+
+func TestItShortensAURLWithRedirectRules(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "http://bbc.co.uk", "rules": [{"url": "HTTPS://apps.apple.com/bbc", "platforms": ["ios"], "from": "09:00", "until": "17:00"}]}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	w = httptest.NewRecorder()
+	apiHandler(w, httptest.NewRequest("GET", "http://localhost:8080/api/links", nil))
+
+	json := responseservice.ParseJSON(w.Result())
+
+	links := json["data"].(map[string]interface{})["links"].([]interface{})
+	if len(links) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 link, instead received '%+v'", links))
+	}
+
+	rules := fmt.Sprintf("%v", links[0].(map[string]interface{})["rules"])
+	expectedRules := "[map[from:09:00 platforms:[ios] until:17:00 url:https://apps.apple.com/bbc]]"
+	if rules != expectedRules {
+		t.Error(fmt.Sprintf("Expected rules '%s', instead received '%s'", expectedRules, rules))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItShortensAnAlreadyShortenedURLWithRedirectRulesAsANewLink(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1":{"long":"http://bbc.co.uk"}}`)
+
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "http://bbc.co.uk", "rules": [{"url": "https://apps.apple.com/bbc", "platforms": ["ios"]}]}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	jsonData := responseservice.ParseJSON(resp)["data"].(map[string]interface{})
+
+	if jsonData["shortURL"] == "http://localhost:8080/ABC1" {
+		t.Error("Expected a new short URL, instead received the existing one")
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItFailsToShortenAURLWhenRedirectRulesAreNotValid(t *testing.T) {
+	tests := map[string]string{
+		`[{"platforms": ["ios"]}]`:                                      "`rules[0].url` is a non-string or missing",
+		`[{"url": "http://bbc.co.uk", "platforms": "ios"}]`:             "`rules[0].platforms` is a non-array of strings",
+		`[{"url": "http://bbc.co.uk", "platforms": ["blackberry"]}]`:    "`rules[0]`: Unknown platform `blackberry`",
+		`[{"url": "http://bbc.co.uk"}, {"url": "ftp://bbc.co.uk/app"}]`: "`rules[1]`: `url` scheme `ftp` is not allowed",
+	}
+
+	for rules, expectedMessage := range tests {
+		w := httptest.NewRecorder()
+
+		r := httptest.NewRequest(
+			"POST",
+			"http://localhost:8080/api/shorten",
+			strings.NewReader(`{"url": "http://bbc.co.uk", "rules": `+rules+`}`),
+		)
+		r.Header = map[string][]string{
+			"Content-Type": {"application/json"},
+		}
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusBadRequest, resp.StatusCode))
+		}
+
+		json := responseservice.ParseJSON(resp)
+
+		jsonData := json["data"].(map[string]interface{})
+		if jsonData["message"] != expectedMessage {
+			t.Error(fmt.Sprintf("Expected message of '%s', instead received '%s'", expectedMessage, jsonData["message"]))
+		}
+	}
+}
+
+func TestItFailsToShortenAURLWhenVariantsAreNotValid(t *testing.T) {
+	tests := map[string]string{
+		`{"url": "http://bbc.co.uk/a"}`:                                                            "`variants` is a non-array",
+		`[{"url": "http://bbc.co.uk/a", "weight": 0}]`:                                             "`variants[0].weight` is not a positive integer",
+		`[{"url": "http://bbc.co.uk/a", "weight": 1.5}]`:                                           "`variants[0].weight` is not a positive integer",
+		`[{"url": "http://bbc.co.uk/a", "name": "x"}, {"url": "http://bbc.co.uk/b", "name": "x"}]`: "`variants[1].name` `x` is not unique",
+	}
+
+	for variants, expectedMessage := range tests {
+		w := httptest.NewRecorder()
+
+		r := httptest.NewRequest(
+			"POST",
+			"http://localhost:8080/api/shorten",
+			strings.NewReader(`{"url": "http://bbc.co.uk", "variants": `+variants+`}`),
+		)
+		r.Header = map[string][]string{
+			"Content-Type": {"application/json"},
+		}
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusBadRequest, resp.StatusCode))
+		}
+
+		json := responseservice.ParseJSON(resp)
+
+		jsonData := json["data"].(map[string]interface{})
+		if jsonData["message"] != expectedMessage {
+			t.Error(fmt.Sprintf("Expected message of '%s', instead received '%s'", expectedMessage, jsonData["message"]))
+		}
+	}
+}
+
+func TestItShortensAURLWithAPassword(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost:8080/api/shorten",
+		strings.NewReader(`{"url": "http://bbc.co.uk", "password": "open sesame"}`),
+	)
+	r.Header = map[string][]string{
+		"Content-Type": {"application/json"},
+	}
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	// the password is stored hashed
+	stored, _ := ioutil.ReadFile(getTestDataPath())
+	if strings.Contains(string(stored), "open sesame") || !strings.Contains(string(stored), `"passwordHash":"pbkdf2-sha256$`) {
+		t.Error(fmt.Sprintf("Expected only the password's hash to be stored, instead received '%s'", stored))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItShortensAURLAsANewOneTimeLinkEachTime(t *testing.T) {
+	shortURLs := map[string]bool{}
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+
+		r := httptest.NewRequest(
+			"POST",
+			"http://localhost:8080/api/shorten",
+			strings.NewReader(`{"url": "http://bbc.co.uk", "maxClicks": 1}`),
+		)
+		r.Header = map[string][]string{
+			"Content-Type": {"application/json"},
+		}
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+		}
+
+		jsonData := responseservice.ParseJSON(resp)["data"].(map[string]interface{})
+		shortURLs[fmt.Sprint(jsonData["shortURL"])] = true
+	}
+
+	if len(shortURLs) != 2 {
+		t.Error(fmt.Sprintf("Expected %d different short URLs, instead received '%v'", 2, shortURLs))
+	}
+
+	// the maximum is stored with the link
+	stored, _ := ioutil.ReadFile(getTestDataPath())
+	if strings.Count(string(stored), `"maxClicks":1`) != 2 {
+		t.Error(fmt.Sprintf("Expected both links to be stored with max clicks, instead received '%s'", stored))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItFailsToShortenAURLWhenMaxClicksIsNotValid(t *testing.T) {
+	for _, maxClicks := range []string{`"1"`, `-1`, `1.5`} {
+		w := httptest.NewRecorder()
+
+		r := httptest.NewRequest(
+			"POST",
+			"http://localhost:8080/api/shorten",
+			strings.NewReader(`{"url": "http://bbc.co.uk", "maxClicks": `+maxClicks+`}`),
+		)
+		r.Header = map[string][]string{
+			"Content-Type": {"application/json"},
+		}
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("Expected status code %d for %s, instead received %d", http.StatusBadRequest, maxClicks, resp.StatusCode))
+		}
+
+		jsonData := responseservice.ParseJSON(resp)["data"].(map[string]interface{})
+
+		expectedMessage := "`maxClicks` is a non-integer or negative"
+		if jsonData["message"] != expectedMessage {
+			t.Error(fmt.Sprintf("Expected message of '%s', instead received '%s'", expectedMessage, jsonData["message"]))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItFailsToShortenAURLWhenTheActiveWindowIsNotValid(t *testing.T) {
+	tests := map[string]string{
+		`"activeFrom": 1767225600`:       "`activeFrom` is a non-string",
+		`"activeFrom": "1 January 2026"`: "`activeFrom` is not an RFC 3339 time",
+		`"activeFrom": "2026-02-01T00:00:00Z", "activeUntil": "2026-01-01T00:00:00Z"`: "`activeUntil` is not after `activeFrom`",
+	}
+
+	for window, expectedMessage := range tests {
+		w := httptest.NewRecorder()
+
+		r := httptest.NewRequest(
+			"POST",
+			"http://localhost:8080/api/shorten",
+			strings.NewReader(`{"url": "http://bbc.co.uk", `+window+`}`),
+		)
+		r.Header = map[string][]string{
+			"Content-Type": {"application/json"},
+		}
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("Expected status code %d for %s, instead received %d", http.StatusBadRequest, window, resp.StatusCode))
+		}
+
+		jsonData := responseservice.ParseJSON(resp)["data"].(map[string]interface{})
+		if jsonData["message"] != expectedMessage {
+			t.Error(fmt.Sprintf("Expected message of '%s', instead received '%s'", expectedMessage, jsonData["message"]))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
 func setTestData(data string) {
 	clearTestData()
 	ioutil.WriteFile(getTestDataPath(), []byte(data), 0644)
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"http-url-shortener/internal/services/responseservice"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestItReturnsOkWhenHealthIsRequested(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8080/healthz", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	if json["status"] != "ok" {
+		t.Error(fmt.Sprintf("Expected JSON status of 'ok', instead received '%s'", json["status"]))
+	}
+}
+
+func TestItReturnsOkWhenReadinessIsRequested(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8080/readyz", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["ready"] != "filesystem" {
+		t.Error(fmt.Sprintf("Expected ready backend of 'filesystem', instead received '%s'", jsonData["ready"]))
+	}
+}
+
+func TestItReturnsBuildDetailsWhenVersionIsRequested(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8080/version", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	for _, key := range []string{"version", "commit", "storage"} {
+		if _, ok := jsonData[key]; !ok {
+			t.Error(fmt.Sprintf("Expected '%s' in version payload, instead received '%+v'", key, jsonData))
+		}
+	}
+}
+
+func TestItReturnsPrometheusMetricsWhenMetricsAreRequested(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8080/metrics", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "# TYPE shortener_redirects_total counter") {
+		t.Error(fmt.Sprintf("Expected redirect counter in metrics, instead received '%s'", body))
+	}
+}
+
+func TestItReturnsMethodNotAllowedWhenProbeIsPosted(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://localhost:8080/healthz", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusMethodNotAllowed, resp.StatusCode))
+	}
+}
+
+func TestItDoesNotRedirectAReservedShortCode(t *testing.T) {
+	// set expected data
+	setTestData(`{"http://bbc.co.uk": "api"}`)
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/api", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusNotFound, resp.StatusCode))
+	}
+
+	// clean up
+	clearTestData()
+}
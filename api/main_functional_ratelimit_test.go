@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"http-url-shortener/internal/services/configservice"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestItSelectsARateLimitForEveryRouteButTheHealthProbes(t *testing.T) {
+	limiter := newRateLimiter(configservice.Config{
+		ShortenRateLimit:  10,
+		RedirectRateLimit: 600,
+		APIRateLimit:      120,
+	})
+
+	redirect := limiter(httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil))
+	other := limiter(httptest.NewRequest("GET", "http://localhost:8080/version", nil))
+
+	if redirect == nil || other == nil || redirect == other {
+		t.Fatal(fmt.Sprintf("Expected separate redirect and default limits, instead received '%p' and '%p'", redirect, other))
+	}
+
+	tests := map[string]bool{
+		"GET http://localhost:8080/ABC1+":             true,
+		"GET http://localhost:8080/ABC1?preview":      true,
+		"POST http://localhost:8080/ABC1":             true,
+		"PUT http://localhost:8080/api/links/ABC1":    false,
+		"DELETE http://localhost:8080/api/links/ABC1": false,
+		"GET http://localhost:8080/api/links":         false,
+	}
+
+	for request, isRedirect := range tests {
+		var method, target string
+		fmt.Sscan(request, &method, &target)
+
+		expected := other
+		if isRedirect {
+			expected = redirect
+		}
+
+		if l := limiter(httptest.NewRequest(method, target, nil)); l != expected {
+			t.Error(fmt.Sprintf("Expected %s to be limited by '%p', instead received '%p'", request, expected, l))
+		}
+	}
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if l := limiter(httptest.NewRequest("GET", "http://localhost:8080"+path, nil)); l != nil {
+			t.Error(fmt.Sprintf("Expected %s to not be limited, instead received '%p'", path, l))
+		}
+	}
+}
+
+func TestItRateLimitsRequestsBearingCredentialsBeforeAuthenticating(t *testing.T) {
+	limiter := newAuthRateLimiter(configservice.Config{AuthRateLimit: 600})
+
+	if l := limiter(httptest.NewRequest("GET", "http://localhost:8080/api/links", nil)); l != nil {
+		t.Error(fmt.Sprintf("Expected a request without credentials to not be limited, instead received '%p'", l))
+	}
+
+	for _, header := range []string{"X-API-Key", "Authorization"} {
+		r := httptest.NewRequest("GET", "http://localhost:8080/api/links", nil)
+		r.Header.Set(header, "guess")
+
+		if l := limiter(r); l == nil {
+			t.Error(fmt.Sprintf("Expected a request bearing %s to be limited", header))
+		}
+	}
+}
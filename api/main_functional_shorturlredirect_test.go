@@ -2,10 +2,20 @@ package main
 
 import (
 	"fmt"
+	"http-url-shortener/internal/handlers"
+	"http-url-shortener/internal/services/authservice"
+	"http-url-shortener/internal/services/domainpolicyservice"
+	"http-url-shortener/internal/services/geoipservice"
+	"http-url-shortener/internal/services/passwordservice"
+	"http-url-shortener/internal/services/ratelimitservice"
+	"http-url-shortener/internal/services/responseservice"
+	"http-url-shortener/internal/services/shortcodeservice"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestItReturnsNotFoundWhenRootIsRequested(t *testing.T) {
@@ -58,15 +68,70 @@ func TestItReturnsNotFoundWhenURLShortCodeDoesNotExist(t *testing.T) {
 		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusNotFound, resp.StatusCode))
 	}
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	if len(string(body)) != 0 {
-		t.Error(fmt.Sprintf("Expected empty body, instead received '%s'", body))
+	jsonData := responseservice.ParseJSON(resp)["data"].(map[string]interface{})
+
+	expectedMessage := "Shortened URL does not exist"
+	if jsonData["message"] != expectedMessage {
+		t.Error(fmt.Sprintf("Expected message of '%s', instead received '%s'", expectedMessage, jsonData["message"]))
 	}
 
 	// clean up
 	clearTestData()
 }
 
+func TestItShowsBrowsersANotFoundPageWhenURLShortCodeDoesNotExist(t *testing.T) {
+	defer useOptions(handlers.Options{SiteName: "Example Links", NotFoundSearchURL: "https://www.example.com/search"})()
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/DEF2", nil)
+	r.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusNotFound, resp.StatusCode))
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Error(fmt.Sprintf("Expected content type of 'text/html; charset=utf-8', instead received '%s'", contentType))
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	expected := []string{"Example Links", "http://localhost:8080/DEF2", `<form method="get" action="https://www.example.com/search" role="search">`}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Error(fmt.Sprintf("Expected '%s' to be rendered, instead received '%s'", e, body))
+		}
+	}
+}
+
+func TestItRedirectsBrowsersToTheFallbackURLWhenURLShortCodeDoesNotExist(t *testing.T) {
+	defer useOptions(handlers.Options{NotFoundURL: "https://www.example.com/"})()
+
+	tests := map[string]int{
+		"text/html":        http.StatusFound,
+		"application/json": http.StatusNotFound,
+	}
+
+	for accept, expected := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080/DEF2", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != expected {
+			t.Error(fmt.Sprintf("Expected status code %d for '%s', instead received %d", expected, accept, resp.StatusCode))
+		}
+
+		if expected == http.StatusFound && resp.Header.Get("Location") != "https://www.example.com/" {
+			t.Error(fmt.Sprintf("Expected location of 'https://www.example.com/', instead received '%s'", resp.Header.Get("Location")))
+		}
+	}
+}
+
 func TestItReturnsRedirectWhenURLShortCodeExists(t *testing.T) {
 	// set expected data
 	setTestData(`{"http://bbc.co.uk": "ABC1"}`)
@@ -86,6 +151,10 @@ func TestItReturnsRedirectWhenURLShortCodeExists(t *testing.T) {
 		t.Error(fmt.Sprintf("Expected empty body, instead received '%s'", body))
 	}
 
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		t.Error(fmt.Sprintf("Expected no content type, instead received '%s'", contentType))
+	}
+
 	location := resp.Header.Get("Location")
 	if location != "http://bbc.co.uk" {
 		t.Error(fmt.Sprintf(
@@ -116,3 +185,598 @@ func TestItReturnsMethodNotAllowedWhenInvalidMethodIsUsedOnExistingShortCode(t *
 	// clean up
 	clearTestData()
 }
+
+func TestItReturnsUnavailableForLegalReasonsWhenDestinationHasBeenBlocked(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://login.phish.net/bank"}}`)
+	defer useOptions(handlers.Options{DomainPolicy: newTestDomainPolicy(nil, []string{"*.phish.net"})})()
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusUnavailableForLegalReasons {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusUnavailableForLegalReasons, resp.StatusCode))
+	}
+
+	if resp.Header.Get("Location") != "" {
+		t.Error(fmt.Sprintf("Expected no location header, instead received '%s'", resp.Header.Get("Location")))
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["message"] != "Destination host `login.phish.net` is blocked" {
+		t.Error(fmt.Sprintf("Expected message explaining block, instead received '%s'", jsonData["message"]))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItLimitsHowLongAPermanentRedirectIsCached(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk"}}`)
+	defer useOptions(handlers.Options{RedirectMaxAge: time.Hour})()
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusMovedPermanently, resp.StatusCode))
+	}
+
+	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "max-age=3600" {
+		t.Error(fmt.Sprintf("Expected Cache-Control of '%s', instead received '%s'", "max-age=3600", cacheControl))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItReturnsAPreviewWhenURLShortCodeIsSuffixed(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "title": "BBC", "createdAt": 1577934245, "clicks": 2}}`)
+
+	for _, path := range []string{"/ABC1+", "/ABC1?preview"} {
+		r := httptest.NewRequest("GET", "http://localhost:8080"+path, nil)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+		}
+
+		if resp.Header.Get("Location") != "" {
+			t.Error(fmt.Sprintf("Expected no location header, instead received '%s'", resp.Header.Get("Location")))
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		for _, expected := range []string{"<h1>BBC</h1>", "Created 2 January 2020", "Followed 2 times", `href="http://bbc.co.uk"`} {
+			if !strings.Contains(string(body), expected) {
+				t.Error(fmt.Sprintf("Expected preview to contain '%s', instead received '%s'", expected, body))
+			}
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRefusesToPreviewALinkWithAMaximumNumberOfClicks(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "maxClicks": 1}, "DEF2": {"long": "http://bbc.co.uk", "maxClicks": 1, "clicks": 1}}`)
+
+	tests := map[string]int{
+		"/ABC1+":        http.StatusForbidden,
+		"/ABC1?preview": http.StatusForbidden,
+		"/DEF2+":        http.StatusGone,
+	}
+
+	for path, expected := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080"+path, nil)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != expected {
+			t.Error(fmt.Sprintf("Expected status code %d for %s, instead received %d", expected, path, resp.StatusCode))
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if strings.Contains(string(body), "bbc.co.uk") {
+			t.Error(fmt.Sprintf("Expected the destination of %s to not be revealed, instead received '%s'", path, body))
+		}
+	}
+
+	// the link may still be followed once
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusFound, resp.StatusCode))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItReturnsAPreviewInsteadOfRedirectingWhenLinkHasAnInterstitial(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "interstitial": true}}`)
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusOK, resp.StatusCode))
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "has asked that you see where it goes") || !strings.Contains(string(body), "Followed 1 time<") {
+		t.Error(fmt.Sprintf("Expected interstitial preview, instead received '%s'", body))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItCountsClicksWhenRedirecting(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk"}}`)
+
+	for i := 0; i < 2; i++ {
+		apiHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil))
+	}
+
+	// previews are not counted
+	apiHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost:8080/ABC1+", nil))
+
+	r := newPrincipalRequest("GET", "/api/links", "", authservice.Principal{Scopes: []string{"admin"}})
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	json := responseservice.ParseJSON(resp)
+
+	links := json["data"].(map[string]interface{})["links"].([]interface{})
+	if clicks := links[0].(map[string]interface{})["clicks"]; clicks != float64(2) {
+		t.Error(fmt.Sprintf("Expected %d clicks, instead received '%v'", 2, clicks))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItIgnoresTheQueryStringWhenRedirectingWithoutPassthrough(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk/news"}}`)
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1?utm_source=mail", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if location := resp.Header.Get("Location"); location != "http://bbc.co.uk/news" {
+		t.Error(fmt.Sprintf("Expected location header '%s', instead received '%s'", "http://bbc.co.uk/news", location))
+	}
+
+	// extra path segments are not found
+	r = httptest.NewRequest("GET", "http://localhost:8080/ABC1/uk", nil)
+	w = httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp = w.Result()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusNotFound, resp.StatusCode))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItPassesThroughPathAndQueryWhenRedirecting(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk/news/?lang=en&ref=short", "passthrough": true}}`)
+
+	tests := map[string]map[string]string{
+		handlers.ParamConflictDestination: {
+			"/ABC1?utm_source=mail&ref=mail": "http://bbc.co.uk/news/?lang=en&ref=short&utm_source=mail",
+			"/ABC1/uk/politics%2Fvotes":      "http://bbc.co.uk/news/uk/politics%2Fvotes?lang=en&ref=short",
+			"/ABC1/../admin":                 "",
+		},
+		handlers.ParamConflictRequest: {
+			"/ABC1?ref=mail&utm_source=mail": "http://bbc.co.uk/news/?lang=en&ref=mail&utm_source=mail",
+		},
+		handlers.ParamConflictAppend: {
+			"/ABC1?ref=mail": "http://bbc.co.uk/news/?lang=en&ref=short&ref=mail",
+		},
+	}
+
+	for conflict, paths := range tests {
+		restore := useOptions(handlers.Options{ParamConflict: conflict})
+
+		for path, expected := range paths {
+			r := httptest.NewRequest("GET", "http://localhost:8080"+path, nil)
+			w := httptest.NewRecorder()
+
+			apiHandler(w, r)
+			resp := w.Result()
+
+			if location := resp.Header.Get("Location"); location != expected {
+				t.Error(fmt.Sprintf("Expected '%s' location header '%s', instead received '%s'", path, expected, location))
+			}
+		}
+
+		restore()
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRedirectsAShortCodeRegardlessOfCaseAndConfusableCharacters(t *testing.T) {
+	// set expected data
+	setTestData(`{"AB01": {"long": "http://bbc.co.uk"}, "legacy": {"long": "http://wikipedia.org"}}`)
+	defer useOptions(handlers.Options{ShortCodes: shortcodeservice.Canonicaliser{Confusables: true}})()
+
+	tests := map[string]string{
+		"/AB01":   "http://bbc.co.uk",
+		"/ab01":   "http://bbc.co.uk",
+		"/abOl":   "http://bbc.co.uk",
+		"/legacy": "http://wikipedia.org",
+	}
+
+	for path, expected := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080"+path, nil)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if location := resp.Header.Get("Location"); location != expected {
+			t.Error(fmt.Sprintf("Expected '%s' location header '%s', instead received '%s'", path, expected, location))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRedirectsToTheDestinationOfTheFirstMatchingRule(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "rules": [
+		{"url": "https://apps.apple.com/bbc", "platforms": ["ios"]},
+		{"url": "https://play.google.com/bbc", "platforms": ["android"]},
+		{"url": "http://bbc.co.uk/cymru", "languages": ["cy"], "countries": ["GB"]}
+	]}}`)
+	defer useOptions(handlers.Options{GeoIP: geoipservice.Mock{Countries: map[string]string{"192.0.2.1": "GB"}}})()
+
+	tests := []struct {
+		userAgent      string
+		acceptLanguage string
+		expected       string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", "cy", "https://apps.apple.com/bbc"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8)", "en-GB", "https://play.google.com/bbc"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "cy-GB,en;q=0.5", "http://bbc.co.uk/cymru"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "en-GB", "http://bbc.co.uk"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+		r.Header.Set("User-Agent", test.userAgent)
+		r.Header.Set("Accept-Language", test.acceptLanguage)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if location := resp.Header.Get("Location"); location != test.expected {
+			t.Error(fmt.Sprintf("Expected '%s' location header '%s', instead received '%s'", test.userAgent, test.expected, location))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSplitsVisitorsBetweenVariantsAndKeepsThemAssigned(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "variants": [
+		{"name": "a", "url": "http://bbc.co.uk/a", "weight": 70},
+		{"name": "b", "url": "http://bbc.co.uk/b", "weight": 30}
+	]}}`)
+
+	// a new visitor is assigned a variant, and sent to its destination
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "variant_ABC1" {
+		t.Fatal(fmt.Sprintf("Expected a variant cookie, instead received '%+v'", cookies))
+	}
+
+	expectedLocation := "http://bbc.co.uk/" + cookies[0].Value
+	if location := resp.Header.Get("Location"); location != expectedLocation {
+		t.Error(fmt.Sprintf("Expected location header '%s', instead received '%s'", expectedLocation, location))
+	}
+
+	// a returning visitor is sent to the variant they were assigned
+	for i := 0; i < 5; i++ {
+		r = httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+		r.AddCookie(&http.Cookie{Name: "variant_ABC1", Value: "b"})
+		w = httptest.NewRecorder()
+
+		apiHandler(w, r)
+
+		if location := w.Result().Header.Get("Location"); location != "http://bbc.co.uk/b" {
+			t.Error(fmt.Sprintf("Expected location header '%s', instead received '%s'", "http://bbc.co.uk/b", location))
+		}
+	}
+
+	// clicks are broken down by variant
+	w = httptest.NewRecorder()
+	apiHandler(w, httptest.NewRequest("GET", "http://localhost:8080/api/links", nil))
+
+	json := responseservice.ParseJSON(w.Result())
+
+	link := json["data"].(map[string]interface{})["links"].([]interface{})[0].(map[string]interface{})
+	clicks := map[string]float64{}
+	for _, v := range link["variants"].([]interface{}) {
+		clicks[v.(map[string]interface{})["name"].(string)] = v.(map[string]interface{})["clicks"].(float64)
+	}
+
+	if clicks["a"]+clicks["b"] != 6 || clicks["b"] < 5 {
+		t.Error(fmt.Sprintf("Expected 6 clicks, at least 5 of them on variant 'b', instead received '%+v'", clicks))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItAsksForThePasswordOfAProtectedLink(t *testing.T) {
+	// set expected data
+	setTestData(fmt.Sprintf(`{"ABC1": {"long": "http://bbc.co.uk", "passwordHash": "%s"}}`, getTestPasswordHash(t, "open sesame")))
+
+	tests := map[string]string{
+		"/ABC1":  "/ABC1",
+		"/ABC1+": "/ABC1&#43;",
+	}
+
+	for path, action := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080"+path, nil)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusUnauthorized, resp.StatusCode))
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if !strings.Contains(string(body), `<form method="post" action="`+action+`">`) || strings.Contains(string(body), "bbc.co.uk") {
+			t.Error(fmt.Sprintf("Expected a password form that doesn't reveal the destination, instead received '%s'", body))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRedirectsAProtectedLinkGivenItsPasswordInAHeader(t *testing.T) {
+	// set expected data
+	setTestData(fmt.Sprintf(`{"ABC1": {"long": "http://bbc.co.uk", "passwordHash": "%s"}}`, getTestPasswordHash(t, "open sesame")))
+
+	tests := map[string]int{
+		"open sesame": http.StatusFound,
+		"wrong":       http.StatusUnauthorized,
+	}
+
+	for password, expected := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+		r.Header.Set("X-Link-Password", password)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != expected {
+			t.Error(fmt.Sprintf("Expected status code %d for password '%s', instead received %d", expected, password, resp.StatusCode))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItUnlocksAProtectedLinkWithACookie(t *testing.T) {
+	// set expected data
+	setTestData(fmt.Sprintf(`{"ABC1": {"long": "http://bbc.co.uk", "passwordHash": "%s"}}`, getTestPasswordHash(t, "open sesame")))
+	defer useOptions(handlers.Options{Unlocker: passwordservice.Unlocker{Key: []byte("secret"), TTL: time.Hour}})()
+
+	r := httptest.NewRequest("POST", "http://localhost:8080/ABC1", strings.NewReader("password=open+sesame"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/ABC1" {
+		t.Error(fmt.Sprintf("Expected to be sent back to '/ABC1', instead received %d '%s'", resp.StatusCode, resp.Header.Get("Location")))
+	}
+
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "unlock_ABC1" || cookies[0].HttpOnly != true {
+		t.Fatal(fmt.Sprintf("Expected an unlock cookie, instead received '%+v'", cookies))
+	}
+
+	r = httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+
+	apiHandler(w, r)
+
+	if location := w.Result().Header.Get("Location"); location != "http://bbc.co.uk" {
+		t.Error(fmt.Sprintf("Expected location header '%s', instead received '%s'", "http://bbc.co.uk", location))
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItLimitsPasswordAttemptsOnAProtectedLink(t *testing.T) {
+	// set expected data
+	setTestData(fmt.Sprintf(`{"ABC1": {"long": "http://bbc.co.uk", "passwordHash": "%s"}}`, getTestPasswordHash(t, "open sesame")))
+	defer useOptions(handlers.Options{UnlockLimiter: ratelimitservice.New(1, 1)})()
+
+	expected := []int{http.StatusUnauthorized, http.StatusTooManyRequests}
+	for _, code := range expected {
+		r := httptest.NewRequest("POST", "http://localhost:8080/ABC1", strings.NewReader("password=wrong"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != code {
+			t.Error(fmt.Sprintf("Expected status code %d, instead received %d", code, resp.StatusCode))
+		}
+
+		if code == http.StatusTooManyRequests && resp.Header.Get("Retry-After") == "" {
+			t.Error("Expected a Retry-After header")
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func getTestPasswordHash(t *testing.T, password string) string {
+	hash, err := passwordservice.Hash(password)
+	if err != nil {
+		t.Fatalf("Unable to hash password: %s", err.Error())
+	}
+
+	return hash
+}
+
+func newTestDomainPolicy(allow []string, block []string) domainpolicyservice.Policy {
+	a, _ := domainpolicyservice.NewList(allow)
+	b, _ := domainpolicyservice.NewList(block)
+
+	return domainpolicyservice.Policy{Allow: a, Block: b}
+}
+
+func TestItRedirectsAOneTimeLinkOnlyOnce(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "maxClicks": 1}}`)
+
+	expected := []int{http.StatusFound, http.StatusGone, http.StatusGone}
+
+	for i, code := range expected {
+		r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != code {
+			t.Error(fmt.Sprintf("Expected status code %d for click %d, instead received %d", code, i+1, resp.StatusCode))
+		}
+
+		// the redirect mustn't be cached, or later clicks would never reach the shortener
+		if code == http.StatusFound && resp.Header.Get("Cache-Control") != "no-store" {
+			t.Error(fmt.Sprintf("Expected Cache-Control of 'no-store', instead received '%s'", resp.Header.Get("Cache-Control")))
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItOnlyRedirectsALinkDuringItsActiveWindow(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "activeFrom": 1767225600, "activeUntil": 1769904000}}`)
+
+	tests := map[string]int{
+		"2025-12-31T23:59:59Z": http.StatusNotFound,
+		"2026-01-15T12:00:00Z": http.StatusFound,
+		"2026-02-01T00:00:00Z": http.StatusGone,
+	}
+
+	for at, expected := range tests {
+		now, _ := time.Parse(time.RFC3339, at)
+		restore := useOptions(handlers.Options{Clock: func() time.Time { return now }})
+
+		r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+		w := httptest.NewRecorder()
+
+		apiHandler(w, r)
+		resp := w.Result()
+		restore()
+
+		if resp.StatusCode != expected {
+			t.Error(fmt.Sprintf("Expected status code %d at %s, instead received %d", expected, at, resp.StatusCode))
+		}
+
+		if expected == http.StatusFound && resp.Header.Get("Cache-Control") != "no-store" {
+			t.Error(fmt.Sprintf("Expected Cache-Control of 'no-store' at %s, instead received '%s'", at, resp.Header.Get("Cache-Control")))
+		}
+
+		if expected == http.StatusNotFound {
+			body, _ := ioutil.ReadAll(resp.Body)
+			if !strings.Contains(string(body), "This link is not yet available") {
+				t.Error(fmt.Sprintf("Expected the not yet available page, instead received '%s'", body))
+			}
+		}
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRedirectsAnInactiveLinkToTheFallbackURL(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "activeFrom": 1767225600}}`)
+	now := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	defer useOptions(handlers.Options{InactiveURL: "http://example.com/coming-soon", Clock: func() time.Time { return now }})()
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	apiHandler(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Error(fmt.Sprintf("Expected status code %d, instead received %d", http.StatusFound, resp.StatusCode))
+	}
+
+	if location := resp.Header.Get("Location"); location != "http://example.com/coming-soon" {
+		t.Error(fmt.Sprintf("Expected location of 'http://example.com/coming-soon', instead received '%s'", location))
+	}
+
+	// clean up
+	clearTestData()
+}
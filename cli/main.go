@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"http-url-shortener/internal/entities/apikey"
+	"http-url-shortener/internal/repositories/apikeyfilesystemrepository"
+	"http-url-shortener/internal/services/apikeyservice"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/browser"
@@ -15,9 +19,17 @@ import (
 type handler struct {
 	command string
 	param   string
+	args    []string
+}
+
+// cliConfig represents the optional CLI config file
+type cliConfig struct {
+	APIKey string `json:"apiKey"`
 }
 
 const apiBaseURL = "http://localhost:8080"
+const apiKeyEnv = "SHORTENER_API_KEY"
+const configFileName = ".shortener.json"
 
 func main() {
 	cliCmd := "go run cli/main.go"
@@ -29,6 +41,11 @@ func main() {
 		"redirect": commandRedirect,
 	}
 
+	// admin commands operate on the local data store directly
+	adminCommands := map[string]func(args []string) error{
+		"keys": commandKeys,
+	}
+
 	if commands[handler.command] != nil {
 		err := commands[handler.command](handler.param)
 		if err != nil {
@@ -38,10 +55,22 @@ func main() {
 		return
 	}
 
+	if adminCommands[handler.command] != nil {
+		err := adminCommands[handler.command](handler.args)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+		}
+
+		return
+	}
+
 	// fallback (no command supplied)
 	fmt.Println("Usage:")
-	fmt.Printf("%s shorten <url>           Shorten a long URL\n", cliCmd)
-	fmt.Printf("%s redirect <shortcode>    Redirect a shortcode to original URL\n", cliCmd)
+	fmt.Printf("%s shorten <url>                    Shorten a long URL\n", cliCmd)
+	fmt.Printf("%s redirect <shortcode>             Redirect a shortcode to original URL\n", cliCmd)
+	fmt.Printf("%s keys create <name> [scopes]      Mint an API key (scopes are comma separated, default \"create\")\n", cliCmd)
+	fmt.Printf("%s keys revoke <id>                 Revoke an API key\n", cliCmd)
+	fmt.Printf("%s keys list                        List API keys\n", cliCmd)
 }
 
 func newHandler(args []string) handler {
@@ -59,6 +88,7 @@ func newHandler(args []string) handler {
 	return handler{
 		command: command,
 		param:   param,
+		args:    args[2:],
 	}
 }
 
@@ -69,15 +99,25 @@ func commandShorten(param string) error {
 
 	// build request payload and make request
 	requestPayload := fmt.Sprintf("{\"url\": \"%s\"}", param)
-	resp, err := http.Post(
+	req, err := http.NewRequest(
+		"POST",
 		fmt.Sprintf("%s/api/shorten", apiBaseURL),
-		"application/json",
 		strings.NewReader(requestPayload),
 	)
 	if err != nil {
 		return errors.New(err.Error())
 	}
 
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey := getAPIKey(); apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+
 	// read response body
 	body := []byte{}
 	body, err = ioutil.ReadAll(resp.Body)
@@ -93,14 +133,25 @@ func commandShorten(param string) error {
 	}
 
 	// check if response body indicates a success
-	if parsed["status"].(string) != "ok" {
-		message := parsed["data"].(map[string]interface{})["message"]
-		return errors.New(message.(string))
+	status, _ := parsed["status"].(string)
+	data, _ := parsed["data"].(map[string]interface{})
+
+	if status != "ok" {
+		message, ok := data["message"].(string)
+		if !ok {
+			return fmt.Errorf("Status %d, unexpected response", resp.StatusCode)
+		}
+
+		return errors.New(message)
 	}
 
 	// print short URL
-	shortURL := parsed["data"].(map[string]interface{})["shortURL"]
-	fmt.Println(shortURL.(string))
+	shortURL, ok := data["shortURL"].(string)
+	if !ok {
+		return fmt.Errorf("Status %d, response is missing short URL", resp.StatusCode)
+	}
+
+	fmt.Println(shortURL)
 
 	return nil
 }
@@ -149,3 +200,86 @@ func commandRedirect(param string) error {
 
 	return nil
 }
+
+func commandKeys(args []string) error {
+	if len(args) == 0 {
+		return errors.New("Please supply a keys command (create, revoke or list)")
+	}
+
+	workdir, _ := os.Getwd()
+	repo := apikeyfilesystemrepository.New(workdir + "/data")
+
+	switch args[0] {
+	case "create":
+		if len(args) < 2 {
+			return errors.New("Please supply a name for the API key")
+		}
+
+		scopes := []string{apikey.ScopeCreate}
+		if len(args) > 2 {
+			scopes = strings.Split(args[2], ",")
+		}
+
+		secret, k, err := apikeyservice.Mint(repo, args[1], scopes)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created API key %s (%s) with scopes %s\n", k.GetID(), k.GetName(), strings.Join(k.GetScopes(), ","))
+		fmt.Printf("Secret (shown once): %s\n", secret)
+
+	case "revoke":
+		if len(args) < 2 {
+			return errors.New("Please supply the ID of the API key to revoke")
+		}
+
+		k, err := repo.Revoke(args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked API key %s (%s)\n", k.GetID(), k.GetName())
+
+	case "list":
+		keys, err := repo.List()
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			status := "active"
+			if k.IsRevoked() {
+				status = "revoked"
+			}
+
+			fmt.Printf("%s  %-20s  %-30s  %s\n", k.GetID(), k.GetName(), strings.Join(k.GetScopes(), ","), status)
+		}
+
+	default:
+		return fmt.Errorf("Unknown keys command '%s'", args[0])
+	}
+
+	return nil
+}
+
+// getAPIKey returns the API key from the environment, falling back to the config file in the user's home directory
+func getAPIKey() string {
+	if apiKey := os.Getenv(apiKeyEnv); apiKey != "" {
+		return apiKey
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(home, configFileName))
+	if err != nil {
+		return ""
+	}
+
+	config := cliConfig{}
+	json.Unmarshal(contents, &config)
+
+	return config.APIKey
+}
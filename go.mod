@@ -1,5 +1,5 @@
 module http-url-shortener
 
-go 1.13
+go 1.21
 
 require github.com/pkg/browser v0.0.0-20180916011732-0a3d74bf9ce4
@@ -0,0 +1,94 @@
+package apikey
+
+import "time"
+
+// Scopes that can be granted to an API key
+const (
+	ScopeCreate    = "create"
+	ScopeUpdate    = "update"
+	ScopeDelete    = "delete"
+	ScopeReadStats = "read-stats"
+	ScopeAdmin     = "admin"
+)
+
+// AllScopes lists every scope that can be granted to an API key
+var AllScopes = []string{ScopeCreate, ScopeUpdate, ScopeDelete, ScopeReadStats, ScopeAdmin}
+
+// APIKey type represents a hashed API key and the scopes it grants
+type APIKey struct {
+	id        string
+	name      string
+	hash      string
+	scopes    []string
+	createdAt time.Time
+	revoked   bool
+}
+
+// New creates a new instance of type APIKey
+func New(id string, name string, hash string, scopes []string, createdAt time.Time) APIKey {
+	return APIKey{
+		id:        id,
+		name:      name,
+		hash:      hash,
+		scopes:    scopes,
+		createdAt: createdAt,
+	}
+}
+
+// GetID retrieves value of APIKey instance's `id` property
+func (k APIKey) GetID() string {
+	return k.id
+}
+
+// GetName retrieves value of APIKey instance's `name` property
+func (k APIKey) GetName() string {
+	return k.name
+}
+
+// GetHash retrieves value of APIKey instance's `hash` property
+func (k APIKey) GetHash() string {
+	return k.hash
+}
+
+// GetScopes retrieves value of APIKey instance's `scopes` property
+func (k APIKey) GetScopes() []string {
+	return k.scopes
+}
+
+// GetCreatedAt retrieves value of APIKey instance's `createdAt` property
+func (k APIKey) GetCreatedAt() time.Time {
+	return k.createdAt
+}
+
+// IsRevoked determines whether the APIKey has been revoked
+func (k APIKey) IsRevoked() bool {
+	return k.revoked
+}
+
+// Revoke returns a revoked copy of the APIKey
+func (k APIKey) Revoke() APIKey {
+	k.revoked = true
+	return k
+}
+
+// HasScope determines whether the APIKey grants the provided scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsValidScope determines whether the provided scope can be granted to an API key
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
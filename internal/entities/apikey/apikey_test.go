@@ -0,0 +1,66 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItSuccessfullyReturnsAnAPIKey(t *testing.T) {
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := New("key1", "marketing", "abc123", []string{ScopeCreate}, createdAt)
+
+	if result.GetID() != "key1" {
+		t.Errorf("Expected id of '%s', instead received '%s'", "key1", result.GetID())
+	}
+
+	if result.GetName() != "marketing" {
+		t.Errorf("Expected name of '%s', instead received '%s'", "marketing", result.GetName())
+	}
+
+	if result.GetHash() != "abc123" {
+		t.Errorf("Expected hash of '%s', instead received '%s'", "abc123", result.GetHash())
+	}
+
+	if !result.GetCreatedAt().Equal(createdAt) {
+		t.Errorf("Expected created at of '%s', instead received '%s'", createdAt, result.GetCreatedAt())
+	}
+
+	if result.IsRevoked() != false {
+		t.Errorf("Expected new key not to be revoked")
+	}
+}
+
+func TestItChecksAnAPIKeysScopes(t *testing.T) {
+	result := New("key1", "marketing", "abc123", []string{ScopeCreate, ScopeReadStats}, time.Now())
+
+	if result.HasScope(ScopeCreate) != true {
+		t.Errorf("Expected key to have scope '%s'", ScopeCreate)
+	}
+
+	if result.HasScope(ScopeDelete) != false {
+		t.Errorf("Expected key not to have scope '%s'", ScopeDelete)
+	}
+}
+
+func TestItRevokesAnAPIKey(t *testing.T) {
+	original := New("key1", "marketing", "abc123", []string{ScopeCreate}, time.Now())
+	revoked := original.Revoke()
+
+	if revoked.IsRevoked() != true {
+		t.Errorf("Expected key to be revoked")
+	}
+
+	if original.IsRevoked() != false {
+		t.Errorf("Expected original key to be unchanged")
+	}
+}
+
+func TestItValidatesScopes(t *testing.T) {
+	if IsValidScope(ScopeUpdate) != true {
+		t.Errorf("Expected '%s' to be a valid scope", ScopeUpdate)
+	}
+
+	if IsValidScope("superuser") != false {
+		t.Errorf("Expected '%s' not to be a valid scope", "superuser")
+	}
+}
@@ -0,0 +1,21 @@
+package shortenedurl
+
+// Rule represents a conditional destination of a ShortenedURL, followed by visitors matching every one of its conditions.
+// Empty conditions match any visitor
+type Rule struct {
+	// URL is the destination that matching visitors are redirected to
+	URL string
+
+	// Platforms are the operating systems, determined from the user agent, that the rule matches - e.g. `ios`, `android`
+	Platforms []string
+
+	// Languages are the preferred languages, from the Accept-Language header, that the rule matches - e.g. `en`, `fr-CA`
+	Languages []string
+
+	// Countries are the ISO 3166 country codes, determined from the visitor's IP address, that the rule matches
+	Countries []string
+
+	// From and Until are the `HH:MM` UTC times of day between which the rule matches, wrapping past midnight if From is later
+	From  string
+	Until string
+}
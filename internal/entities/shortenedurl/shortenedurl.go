@@ -1,9 +1,25 @@
 package shortenedurl
 
+import "time"
+
 // ShortenedURL type represents a URL to be handled by the system
 type ShortenedURL struct {
-	long  string
-	short string
+	long         string
+	short        string
+	owner        string
+	threat       string
+	title        string
+	createdAt    time.Time
+	clicks       int
+	interstitial bool
+	passthrough  bool
+	campaign     string
+	rules        []Rule
+	variants     []Variant
+	passwordHash string
+	maxClicks    int
+	activeFrom   time.Time
+	activeUntil  time.Time
 }
 
 // New creates a new instance of type ShortenedURL
@@ -23,3 +39,197 @@ func (u ShortenedURL) GetLong() string {
 func (u ShortenedURL) GetShort() string {
 	return u.short
 }
+
+// GetOwner retrieves value of ShortenedURL instance's `owner` property
+func (u ShortenedURL) GetOwner() string {
+	return u.owner
+}
+
+// WithOwner returns a copy of the ShortenedURL owned by the provided principal
+func (u ShortenedURL) WithOwner(owner string) ShortenedURL {
+	u.owner = owner
+	return u
+}
+
+// WithShort returns a copy of the ShortenedURL with the provided short code
+func (u ShortenedURL) WithShort(short string) ShortenedURL {
+	u.short = short
+	return u
+}
+
+// WithLong returns a copy of the ShortenedURL that redirects to the provided long URL
+func (u ShortenedURL) WithLong(long string) ShortenedURL {
+	u.long = long
+	return u
+}
+
+// GetThreat retrieves value of ShortenedURL instance's `threat` property
+func (u ShortenedURL) GetThreat() string {
+	return u.threat
+}
+
+// IsQuarantined determines whether the ShortenedURL's destination has been flagged as a threat
+func (u ShortenedURL) IsQuarantined() bool {
+	return u.threat != ""
+}
+
+// WithThreat returns a copy of the ShortenedURL flagged with the provided threat type, or cleared if empty
+func (u ShortenedURL) WithThreat(threat string) ShortenedURL {
+	u.threat = threat
+	return u
+}
+
+// GetTitle retrieves value of ShortenedURL instance's `title` property
+func (u ShortenedURL) GetTitle() string {
+	return u.title
+}
+
+// WithTitle returns a copy of the ShortenedURL with the provided title
+func (u ShortenedURL) WithTitle(title string) ShortenedURL {
+	u.title = title
+	return u
+}
+
+// GetCreatedAt retrieves value of ShortenedURL instance's `createdAt` property, which is zero if unknown
+func (u ShortenedURL) GetCreatedAt() time.Time {
+	return u.createdAt
+}
+
+// WithCreatedAt returns a copy of the ShortenedURL created at the provided time
+func (u ShortenedURL) WithCreatedAt(createdAt time.Time) ShortenedURL {
+	u.createdAt = createdAt
+	return u
+}
+
+// GetClicks retrieves value of ShortenedURL instance's `clicks` property
+func (u ShortenedURL) GetClicks() int {
+	return u.clicks
+}
+
+// WithClicks returns a copy of the ShortenedURL that has been followed the provided number of times
+func (u ShortenedURL) WithClicks(clicks int) ShortenedURL {
+	u.clicks = clicks
+	return u
+}
+
+// HasInterstitial determines whether the ShortenedURL always shows a preview of its destination, rather than redirecting
+func (u ShortenedURL) HasInterstitial() bool {
+	return u.interstitial
+}
+
+// WithInterstitial returns a copy of the ShortenedURL that does or does not always show a preview of its destination
+func (u ShortenedURL) WithInterstitial(interstitial bool) ShortenedURL {
+	u.interstitial = interstitial
+	return u
+}
+
+// HasPassthrough determines whether the ShortenedURL passes extra path segments and query parameters through to its destination
+func (u ShortenedURL) HasPassthrough() bool {
+	return u.passthrough
+}
+
+// WithPassthrough returns a copy of the ShortenedURL that does or does not pass extra path segments and query parameters through
+func (u ShortenedURL) WithPassthrough(passthrough bool) ShortenedURL {
+	u.passthrough = passthrough
+	return u
+}
+
+// GetCampaign retrieves value of ShortenedURL instance's `campaign` property
+func (u ShortenedURL) GetCampaign() string {
+	return u.campaign
+}
+
+// WithCampaign returns a copy of the ShortenedURL attributed to the provided marketing campaign
+func (u ShortenedURL) WithCampaign(campaign string) ShortenedURL {
+	u.campaign = campaign
+	return u
+}
+
+// GetRules retrieves value of ShortenedURL instance's `rules` property
+func (u ShortenedURL) GetRules() []Rule {
+	return u.rules
+}
+
+// WithRules returns a copy of the ShortenedURL with the provided ordered rules, evaluated before its default destination
+func (u ShortenedURL) WithRules(rules []Rule) ShortenedURL {
+	u.rules = rules
+	return u
+}
+
+// GetVariants retrieves value of ShortenedURL instance's `variants` property
+func (u ShortenedURL) GetVariants() []Variant {
+	return u.variants
+}
+
+// WithVariants returns a copy of the ShortenedURL that splits visitors between the provided weighted variants
+func (u ShortenedURL) WithVariants(variants []Variant) ShortenedURL {
+	u.variants = variants
+	return u
+}
+
+// GetPasswordHash retrieves value of ShortenedURL instance's `passwordHash` property
+func (u ShortenedURL) GetPasswordHash() string {
+	return u.passwordHash
+}
+
+// WithPasswordHash returns a copy of the ShortenedURL protected by the password of the provided hash, or unprotected if empty
+func (u ShortenedURL) WithPasswordHash(passwordHash string) ShortenedURL {
+	u.passwordHash = passwordHash
+	return u
+}
+
+// IsProtected determines whether the ShortenedURL may only be followed by visitors who enter its password
+func (u ShortenedURL) IsProtected() bool {
+	return u.passwordHash != ""
+}
+
+// GetMaxClicks retrieves value of ShortenedURL instance's `maxClicks` property
+func (u ShortenedURL) GetMaxClicks() int {
+	return u.maxClicks
+}
+
+// WithMaxClicks returns a copy of the ShortenedURL that may only be followed the provided number of times, or any number of times if 0
+func (u ShortenedURL) WithMaxClicks(maxClicks int) ShortenedURL {
+	u.maxClicks = maxClicks
+	return u
+}
+
+// IsExhausted determines whether the ShortenedURL has been followed as many times as it may be
+func (u ShortenedURL) IsExhausted() bool {
+	return u.maxClicks > 0 && u.clicks >= u.maxClicks
+}
+
+// GetActiveFrom retrieves value of ShortenedURL instance's `activeFrom` property, which is zero if the link is active from creation
+func (u ShortenedURL) GetActiveFrom() time.Time {
+	return u.activeFrom
+}
+
+// GetActiveUntil retrieves value of ShortenedURL instance's `activeUntil` property, which is zero if the link never expires
+func (u ShortenedURL) GetActiveUntil() time.Time {
+	return u.activeUntil
+}
+
+// WithActiveWindow returns a copy of the ShortenedURL that may only be followed from and until the provided times, either of which may be zero
+func (u ShortenedURL) WithActiveWindow(from time.Time, until time.Time) ShortenedURL {
+	u.activeFrom = from
+	u.activeUntil = until
+	return u
+}
+
+// IsActive determines whether the ShortenedURL may be followed at the provided time, according to its active window
+func (u ShortenedURL) IsActive(now time.Time) bool {
+	return (u.activeFrom.IsZero() || !now.Before(u.activeFrom)) && (u.activeUntil.IsZero() || now.Before(u.activeUntil))
+}
+
+// IsStatic determines whether the ShortenedURL always redirects every visitor to the same destination, so that its
+// redirect may be cached, which is only the case if it has no rules, variants, password, maximum clicks or active window
+func (u ShortenedURL) IsStatic() bool {
+	return len(u.rules) == 0 && len(u.variants) == 0 && u.passwordHash == "" && u.maxClicks == 0 &&
+		u.activeFrom.IsZero() && u.activeUntil.IsZero()
+}
+
+// IsReusable determines whether the ShortenedURL may be returned to its owner when they shorten its long URL again,
+// which is only the case if it is static, as a new link may not share any of its other properties
+func (u ShortenedURL) IsReusable() bool {
+	return u.IsStatic()
+}
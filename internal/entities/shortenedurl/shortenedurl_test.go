@@ -3,6 +3,7 @@ package shortenedurl
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestItSuccessfullyReturnsAShortenedURL(t *testing.T) {
@@ -24,3 +25,174 @@ func TestItSuccessfullyReturnsAShortenedURL(t *testing.T) {
 		t.Errorf("Expected short value of '%s', instead received '%s'", short, result.GetShort())
 	}
 }
+
+func TestItReturnsAnOwnedCopyOfAShortenedURL(t *testing.T) {
+	original := New("http://bbc.co.uk", "ABC1")
+	owned := original.WithOwner("key1")
+
+	if owned.GetOwner() != "key1" {
+		t.Errorf("Expected owner of '%s', instead received '%s'", "key1", owned.GetOwner())
+	}
+
+	if original.GetOwner() != "" {
+		t.Errorf("Expected original to be unowned, instead received '%s'", original.GetOwner())
+	}
+}
+
+func TestItReturnsACopyOfAShortenedURLWithANewLongURL(t *testing.T) {
+	original := New("http://bbc.co.uk", "ABC1")
+	updated := original.WithLong("http://wikipedia.org")
+
+	if updated.GetLong() != "http://wikipedia.org" || updated.GetShort() != "ABC1" {
+		t.Errorf("Expected updated long URL with same short code, instead received '%+v'", updated)
+	}
+
+	if original.GetLong() != "http://bbc.co.uk" {
+		t.Errorf("Expected original to be unchanged, instead received '%s'", original.GetLong())
+	}
+}
+
+func TestItReturnsAQuarantinedCopyOfAShortenedURL(t *testing.T) {
+	original := New("http://bbc.co.uk", "ABC1")
+	quarantined := original.WithThreat("phishing")
+
+	if quarantined.IsQuarantined() != true || quarantined.GetThreat() != "phishing" {
+		t.Errorf("Expected threat of '%s', instead received '%s'", "phishing", quarantined.GetThreat())
+	}
+
+	if original.IsQuarantined() != false {
+		t.Errorf("Expected original not to be quarantined")
+	}
+}
+
+func TestItReturnsADescribedCopyOfAShortenedURL(t *testing.T) {
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	u := New("http://bbc.co.uk", "ABC1").
+		WithTitle("BBC").
+		WithCreatedAt(createdAt).
+		WithClicks(3).
+		WithInterstitial(true).
+		WithPassthrough(true).
+		WithCampaign("spring").
+		WithRules([]Rule{{URL: "https://apps.apple.com", Platforms: []string{"ios"}}}).
+		WithVariants([]Variant{{Name: "a", URL: "http://bbc.co.uk/a", Weight: 70}, {Name: "b", URL: "http://bbc.co.uk/b", Weight: 30}}).
+		WithPasswordHash("hash")
+
+	if u.GetTitle() != "BBC" {
+		t.Errorf("Expected title of '%s', instead received '%s'", "BBC", u.GetTitle())
+	}
+
+	if u.GetCreatedAt() != createdAt {
+		t.Errorf("Expected creation date of '%s', instead received '%s'", createdAt, u.GetCreatedAt())
+	}
+
+	if u.GetClicks() != 3 {
+		t.Errorf("Expected %d clicks, instead received %d", 3, u.GetClicks())
+	}
+
+	if u.HasInterstitial() != true {
+		t.Errorf("Expected link to have an interstitial")
+	}
+
+	if u.HasPassthrough() != true {
+		t.Errorf("Expected link to have passthrough")
+	}
+
+	if u.GetCampaign() != "spring" {
+		t.Errorf("Expected campaign of '%s', instead received '%s'", "spring", u.GetCampaign())
+	}
+
+	if len(u.GetRules()) != 1 || u.GetRules()[0].URL != "https://apps.apple.com" {
+		t.Errorf("Expected a single rule, instead received '%+v'", u.GetRules())
+	}
+
+	if len(u.GetVariants()) != 2 || u.GetVariants()[1].Weight != 30 {
+		t.Errorf("Expected two variants, instead received '%+v'", u.GetVariants())
+	}
+
+	if u.GetPasswordHash() != "hash" || u.IsProtected() != true {
+		t.Errorf("Expected link to be protected by password hash '%s', instead received '%s'", "hash", u.GetPasswordHash())
+	}
+}
+
+func TestItLimitsAShortenedURLToMaxClicks(t *testing.T) {
+	u := New("http://bbc.co.uk", "ABC1").WithMaxClicks(2).WithClicks(1)
+
+	if u.GetMaxClicks() != 2 || u.IsExhausted() != false {
+		t.Errorf("Expected link with a click remaining, instead received '%+v'", u)
+	}
+
+	if u.WithClicks(2).IsExhausted() != true {
+		t.Errorf("Expected link to be exhausted after %d clicks", 2)
+	}
+
+	if New("http://bbc.co.uk", "ABC1").WithClicks(100).IsExhausted() != false {
+		t.Errorf("Expected link without max clicks to never be exhausted")
+	}
+}
+
+func TestItDeterminesWhetherAShortenedURLIsReusable(t *testing.T) {
+	tests := map[string]ShortenedURL{
+		"rules":     New("http://bbc.co.uk", "").WithRules([]Rule{{URL: "https://apps.apple.com"}}),
+		"variants":  New("http://bbc.co.uk", "").WithVariants([]Variant{{Name: "a", URL: "http://bbc.co.uk/a", Weight: 1}}),
+		"password":  New("http://bbc.co.uk", "").WithPasswordHash("hash"),
+		"maxClicks": New("http://bbc.co.uk", "").WithMaxClicks(1),
+	}
+
+	for name, u := range tests {
+		if u.IsReusable() != false {
+			t.Errorf("Expected link with %s to not be reusable", name)
+		}
+	}
+
+	if New("http://bbc.co.uk", "").WithTitle("BBC").IsReusable() != true {
+		t.Errorf("Expected plain link to be reusable")
+	}
+}
+
+func TestItDeterminesWhetherAShortenedURLIsStatic(t *testing.T) {
+	tests := map[string]ShortenedURL{
+		"rules":       New("http://bbc.co.uk", "").WithRules([]Rule{{URL: "https://apps.apple.com"}}),
+		"variants":    New("http://bbc.co.uk", "").WithVariants([]Variant{{Name: "a", URL: "http://bbc.co.uk/a", Weight: 1}}),
+		"password":    New("http://bbc.co.uk", "").WithPasswordHash("hash"),
+		"maxClicks":   New("http://bbc.co.uk", "").WithMaxClicks(1),
+		"activeUntil": New("http://bbc.co.uk", "").WithActiveWindow(time.Time{}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	for name, u := range tests {
+		if u.IsStatic() != false {
+			t.Errorf("Expected link with %s to not be static", name)
+		}
+	}
+
+	if New("http://bbc.co.uk", "").WithPassthrough(true).IsStatic() != true {
+		t.Errorf("Expected plain link to be static")
+	}
+}
+
+func TestItDeterminesWhetherAShortenedURLIsActive(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[time.Time]bool{
+		from.Add(-time.Second):  false,
+		from:                    true,
+		until.Add(-time.Second): true,
+		until:                   false,
+	}
+
+	u := New("http://bbc.co.uk", "ABC1").WithActiveWindow(from, until)
+	for now, expected := range tests {
+		if u.IsActive(now) != expected {
+			t.Errorf("Expected link active at %s to be %t", now, expected)
+		}
+	}
+
+	if New("http://bbc.co.uk", "ABC1").WithActiveWindow(from, time.Time{}).IsActive(until.AddDate(10, 0, 0)) != true {
+		t.Errorf("Expected link without an end to remain active")
+	}
+
+	if New("http://bbc.co.uk", "ABC1").WithActiveWindow(from, time.Time{}).IsReusable() != false {
+		t.Errorf("Expected link with an active window to not be reusable")
+	}
+}
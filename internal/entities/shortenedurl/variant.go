@@ -0,0 +1,16 @@
+package shortenedurl
+
+// Variant represents one of the weighted destinations that a ShortenedURL's visitors are split between, for experiments
+type Variant struct {
+	// Name identifies the variant that a visitor has been assigned to, and is unique within the ShortenedURL
+	Name string
+
+	// URL is the destination that visitors assigned to the variant are redirected to
+	URL string
+
+	// Weight is the share of visitors assigned to the variant, relative to the weights of the other variants
+	Weight int
+
+	// Clicks is the number of times the variant has been followed
+	Clicks int
+}
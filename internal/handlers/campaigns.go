@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+	"sort"
+)
+
+// GetCampaigns handles request for link and click totals grouped by campaign, restricted to the principal's own links unless they are an admin
+func GetCampaigns(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	principal, ok := getPrincipal(opts, r)
+	if !ok {
+		return responseservice.NewErrResponse("Missing credentials", http.StatusUnauthorized)
+	}
+
+	// admins may total every link, or filter by owner
+	owner := principal.ID
+	if principal.IsAdmin() {
+		owner = r.URL.Query().Get("owner")
+	}
+
+	urls, err := repo.List(owner)
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to list shortened URLs", err, "owner", owner)
+	}
+
+	totals := map[string]map[string]interface{}{}
+	for _, u := range urls {
+		total, ok := totals[u.GetCampaign()]
+		if !ok {
+			total = map[string]interface{}{"campaign": u.GetCampaign(), "links": 0, "clicks": 0}
+			totals[u.GetCampaign()] = total
+		}
+
+		total["links"] = total["links"].(int) + 1
+		total["clicks"] = total["clicks"].(int) + u.GetClicks()
+	}
+
+	// links without a campaign are grouped under an empty campaign, which sorts first
+	campaigns := []map[string]interface{}{}
+	for _, total := range totals {
+		campaigns = append(campaigns, total)
+	}
+
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i]["campaign"].(string) < campaigns[j]["campaign"].(string)
+	})
+
+	return responseservice.NewOkResponse(map[string]interface{}{
+		"campaigns": campaigns,
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"http-url-shortener/internal/services/logservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+)
+
+// fieldError represents a property of a request body that failed validation, and the error code of the failure
+type fieldError struct {
+	field string
+	code  string
+	err   error
+}
+
+func (e fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e fieldError) Unwrap() error {
+	return e.err
+}
+
+// invalidField returns an error identifying the request body property that failed validation
+func invalidField(field string, err error) error {
+	return fieldError{field: field, code: responseservice.CodeInvalidRequest, err: err}
+}
+
+// invalidURL returns an error identifying the request body property whose URL is not valid
+func invalidURL(field string, err error) error {
+	return fieldError{field: field, code: responseservice.CodeInvalidURL, err: err}
+}
+
+// getRequestErrResponse returns the response for a request body that could not be parsed, or failed validation
+func getRequestErrResponse(err error) responseservice.JSONResponse {
+	var invalid fieldError
+	if errors.As(err, &invalid) {
+		return responseservice.NewErrResponse(err.Error(), http.StatusBadRequest).
+			WithErrorCode(invalid.code).
+			WithFieldErrors(responseservice.FieldError{Field: invalid.field, Message: err.Error()})
+	}
+
+	// don't reveal the parser's own wording, which describes Go types rather than the request
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return responseservice.NewErrResponse("Request body is not a valid JSON object", http.StatusBadRequest).
+			WithErrorCode(responseservice.CodeInvalidJSON)
+	}
+
+	return responseservice.NewErrResponse(err.Error(), http.StatusBadRequest)
+}
+
+// getBlockedResponse returns the response for a request body property whose destination is not permitted by the domain policy
+func getBlockedResponse(field string, err error) responseservice.JSONResponse {
+	return responseservice.NewErrResponse(err.Error(), http.StatusForbidden).
+		WithErrorCode(responseservice.CodeBlockedDomain).
+		WithFieldErrors(responseservice.FieldError{Field: field, Message: err.Error()})
+}
+
+// getInternalErrResponse logs an unexpected failure, returning a response that doesn't reveal its details
+func getInternalErrResponse(r *http.Request, message string, err error, args ...any) responseservice.JSONResponse {
+	logservice.FromContext(r.Context()).Error(message, append(args, "error", err)...)
+	return responseservice.NewErrResponse("Internal server error", http.StatusInternalServerError)
+}
@@ -3,97 +3,435 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"http-url-shortener/internal/entities/shortenedurl"
 	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/campaignservice"
+	"http-url-shortener/internal/services/logservice"
 	"http-url-shortener/internal/services/responseservice"
+	"http-url-shortener/internal/services/ruleservice"
 	"http-url-shortener/internal/services/shortcodeservice"
+	"http-url-shortener/internal/services/urlpolicyservice"
+	"http-url-shortener/internal/services/versionservice"
 	"io/ioutil"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
 )
 
 // PostShorten handles request to shorten a URL
 func PostShorten(
 	repo repositoryinterface.RepositoryInterface,
+	opts Options,
 	w http.ResponseWriter,
 	r *http.Request,
 ) responseservice.JSONResponse {
-	// extract URL property from request body
-	urlValue, err := getValueOfURLFromRequestBody(r)
+	principal, ok := getPrincipal(opts, r)
+	if !ok {
+		return responseservice.NewErrResponse("Missing credentials", http.StatusUnauthorized)
+	}
+
+	// extract link properties from request body
+	link, err := getLinkFromRequestBody(r, opts.URLPolicy)
+	if err != nil {
+		return getRequestErrResponse(err)
+	}
+	urlValue := link.URL
+
+	// prevent links to this shortener, which could otherwise form chains or loops
+	urlValue, err = checkSelfLink(repo, opts, r, principal.ID, urlValue)
+	if err != nil {
+		return getRequestErrResponse(invalidURL("url", err))
+	}
+
+	// check that the destination is permitted
+	if err := opts.DomainPolicy.Check(urlValue); err != nil {
+		blockedTotal.Inc("shorten")
+		return getBlockedResponse("url", err)
+	}
+
+	// check that the destination is not a known threat
+	threat, errResponse, ok := checkThreat(opts, r, urlValue)
+	if !ok {
+		return errResponse
+	}
+
+	// check the destinations of any rules and variants in the same way
+	rules, ruleThreat, errResponse, ok := checkRules(repo, opts, r, principal.ID, link.Rules)
+	if !ok {
+		return errResponse
+	}
+
+	variants, variantThreat, errResponse, ok := checkVariants(repo, opts, r, principal.ID, link.Variants)
+	if !ok {
+		return errResponse
+	}
+
+	if threat == "" {
+		threat = ruleThreat
+	}
+
+	if threat == "" {
+		threat = variantThreat
+	}
+
+	passwordHash, err := getPasswordHash(link)
 	if err != nil {
-		return responseservice.NewErrResponse(err.Error(), http.StatusBadRequest)
+		return getInternalErrResponse(r, "Failed to hash password", err)
 	}
 
-	// check if we've already shortened it
-	existing, err := repo.RetrieveByLongURL(urlValue)
-	if err == nil {
-		// return our existing record
-		return responseservice.NewOkResponse(map[string]string{
-			"shortURL": "http://" + r.Host + "/" + existing.GetShort(),
-		})
+	shortened := shortenedurl.New(urlValue, "").
+		WithOwner(principal.ID).
+		WithThreat(threat).
+		WithTitle(link.Title).
+		WithInterstitial(link.Interstitial).
+		WithPassthrough(link.Passthrough).
+		WithCampaign(link.Campaign).
+		WithRules(rules).
+		WithVariants(variants).
+		WithPasswordHash(passwordHash).
+		WithMaxClicks(link.MaxClicks).
+		WithActiveWindow(link.ActiveFrom, link.ActiveUntil).
+		WithCreatedAt(now(opts).UTC())
+
+	// check if we've already shortened it, unless the new link has properties that an existing link can't share
+	if shortened.IsReusable() {
+		existing, err := repo.RetrieveByLongURL(urlValue, principal.ID)
+		if err == nil {
+			// return our existing record
+			shortenedTotal.Inc("existing")
+			return responseservice.NewOkResponse(map[string]string{
+				"shortURL": getBaseURL(r) + "/" + existing.GetShort(),
+			})
+		}
+	}
+
+	// check that the principal has not reached their quota
+	if opts.LinkQuota > 0 && !principal.IsAdmin() {
+		count, err := repo.Count(principal.ID)
+		if err != nil {
+			return getInternalErrResponse(r, "Failed to count shortened URLs", err, "owner", principal.ID)
+		}
+
+		if count >= opts.LinkQuota {
+			return responseservice.NewErrResponse(
+				fmt.Sprintf("Link quota of %d has been reached", opts.LinkQuota),
+				http.StatusForbidden,
+			).WithErrorCode(responseservice.CodeQuotaExceeded)
+		}
 	}
 
 	// URL is new, let's generate a new shortcode
-	shortCode := shortcodeservice.Generate()
+	shortCode := opts.ShortCodes.Generate()
+	_, err = repo.RetrieveByShortCode(shortCode)
 
-	// loop until we have a unique short code...
-	for err == nil {
-		shortCode = shortcodeservice.Generate()
+	// loop until we have a unique, unreserved short code...
+	for err == nil || shortcodeservice.IsReserved(shortCode) {
+		shortCodeCollisionsTotal.Inc()
+		shortCode = opts.ShortCodes.Generate()
 		_, err = repo.RetrieveByShortCode(shortCode)
 	}
 
 	// save our shortened URL
-	shortened, err := repo.Create(shortenedurl.New(urlValue, shortCode))
+	shortened, err = repo.Create(shortened.WithShort(shortCode))
+	if errors.Is(err, repositoryinterface.ErrShortenedURLExists) {
+		// another request has created the same link in the meantime
+		return responseservice.NewErrResponse(err.Error(), http.StatusConflict)
+	}
+
 	if err != nil {
-		return responseservice.NewErrResponse(err.Error())
+		return getInternalErrResponse(r, "Failed to create shortened URL", err, "url", urlValue)
 	}
 
 	// return our new record
+	shortenedTotal.Inc("new")
 	return responseservice.NewOkResponse(map[string]string{
-		"shortURL": "http://" + r.Host + "/" + shortened.GetShort(),
+		"shortURL": getBaseURL(r) + "/" + shortened.GetShort(),
 	})
 }
 
 // GetShortURLRedirect handles request to redirect a short URL
 func GetShortURLRedirect(
 	repo repositoryinterface.RepositoryInterface,
+	opts Options,
 	w http.ResponseWriter,
 	r *http.Request,
 ) responseservice.JSONResponse {
-	pathParts := strings.SplitN(r.URL.Path, "/", 2)
-	if len(pathParts) < 2 {
-		// root path "/" (no short code supplied)
-		return responseservice.NewEmptyResponse(http.StatusNotFound)
-	}
-
-	shortCode := pathParts[1]
-	shortenedURL, err := repo.RetrieveByShortCode(shortCode)
-	if err == nil {
-		// set redirect header to short code's corresponding long URL
-		return responseservice.NewEmptyResponse(
-			http.StatusMovedPermanently,
+	// the short code is the first path segment, and any further segments may be passed through
+	shortCode, extraPath := splitPath(r.URL.EscapedPath())
+
+	shortenedURL, dest, errResponse, ok := getDestination(repo, opts, r, shortCode)
+	if !ok {
+		return errResponse
+	}
+	longURL := dest.URL
+
+	if shortenedURL.HasPassthrough() {
+		passed, err := passThrough(longURL, extraPath, r.URL.RawQuery, opts.ParamConflict)
+		if err != nil {
+			return getNotFoundResponse(opts, r)
+		}
+
+		longURL = passed
+	} else if extraPath != "" {
+		return getNotFoundResponse(opts, r)
+	}
+
+	// count the click, which also claims one of the link's remaining clicks if it has a maximum
+	clicked, err := repo.RecordClick(shortenedURL.GetShort(), dest.Variant)
+	if errors.Is(err, repositoryinterface.ErrClicksExhausted) {
+		exhaustedTotal.Inc()
+		return responseservice.NewEmptyResponse(http.StatusGone)
+	}
+
+	if err != nil {
+		logservice.FromContext(r.Context()).Error("Failed to record click", "shortCode", shortenedURL.GetShort(), "error", err)
+
+		// a link with a maximum can't be followed without its click being claimed, but others needn't fail
+		if shortenedURL.GetMaxClicks() > 0 {
+			return responseservice.NewErrResponse("Click could not be recorded", http.StatusServiceUnavailable)
+		}
+
+		clicked = shortenedURL
+	}
+
+	redirectsTotal.Inc()
+
+	// set redirect header to short code's corresponding long URL, cached only for a while so that clients
+	// eventually see a changed destination, or one that has since been blocked
+	resp := responseservice.NewEmptyResponse(
+		http.StatusMovedPermanently,
+		"Location",
+		longURL,
+		"Cache-Control",
+		fmt.Sprintf("max-age=%d", int(opts.RedirectMaxAge.Seconds())),
+	)
+
+	// a link whose destination or availability may change between visits must reach us on every visit
+	if !shortenedURL.IsStatic() {
+		resp = responseservice.NewEmptyResponse(
+			http.StatusFound,
 			"Location",
-			shortenedURL.GetLong(),
+			longURL,
+			"Cache-Control",
+			"no-store",
 		)
 	}
 
-	// nothing found
-	return responseservice.NewEmptyResponse(http.StatusNotFound)
+	// show where the link goes, rather than redirecting, if its owner has asked to
+	if shortenedURL.HasInterstitial() {
+		resp = getPreviewResponse(r, clicked, longURL)
+	}
+
+	// keep the visitor assigned to the same variant on later visits
+	if dest.Variant != "" {
+		resp = resp.WithHeaders("Set-Cookie", getVariantCookie(shortenedURL, dest.Variant).String())
+	}
+
+	return resp
+}
+
+// GetShortURLPreview handles request to preview where a short URL goes, via the `/{code}+` or `/{code}?preview` routes
+func GetShortURLPreview(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	shortCode, _ := splitPath(r.URL.EscapedPath())
+	shortCode = strings.TrimSuffix(shortCode, "+")
+
+	shortenedURL, dest, errResponse, ok := getDestination(repo, opts, r, shortCode)
+	if !ok {
+		return errResponse
+	}
+
+	// previewing a link limited to a number of clicks would reveal its destination without claiming one
+	if shortenedURL.GetMaxClicks() > 0 {
+		return responseservice.NewErrResponse("Links with a maximum number of clicks can't be previewed", http.StatusForbidden)
+	}
+
+	return getPreviewResponse(r, shortenedURL, dest.URL)
+}
+
+// destination represents where a visitor to a link is sent
+type destination struct {
+	URL string
+
+	// Variant is the name of the variant that the visitor has been assigned to, if the link splits visitors between variants
+	Variant string
+}
+
+// getDestination retrieves the link identified by a short code, and the destination that it may currently be followed to.
+// If it may not be followed, an error (or warning) response is returned instead
+func getDestination(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	r *http.Request,
+	shortCode string,
+) (shortenedurl.ShortenedURL, destination, responseservice.JSONResponse, bool) {
+	if shortCode == "" || shortcodeservice.IsReserved(shortCode) {
+		// root path "/" (no short code supplied), or a reserved path
+		return shortenedurl.ShortenedURL{}, destination{}, getNotFoundResponse(opts, r), false
+	}
+
+	shortenedURL, err := retrieveByShortCode(repo, opts, shortCode)
+	if err != nil {
+		// nothing found
+		notFoundTotal.Inc()
+		return shortenedurl.ShortenedURL{}, destination{}, getNotFoundResponse(opts, r), false
+	}
+
+	// a link that has been followed as many times as it may be is gone for good
+	if shortenedURL.IsExhausted() {
+		exhaustedTotal.Inc()
+		return shortenedurl.ShortenedURL{}, destination{}, responseservice.NewEmptyResponse(http.StatusGone), false
+	}
+
+	// send visitors elsewhere before a link's launch or after its expiry
+	if !shortenedURL.IsActive(now(opts)) {
+		return shortenedurl.ShortenedURL{}, destination{}, getInactiveResponse(opts, r, shortenedURL), false
+	}
+
+	// ask for the password of a protected link, before revealing anything about its destination
+	if errResponse, ok := checkUnlocked(opts, r, shortenedURL); !ok {
+		return shortenedurl.ShortenedURL{}, destination{}, errResponse, false
+	}
+
+	// warn rather than redirect to a destination flagged as a threat
+	if shortenedURL.IsQuarantined() {
+		return shortenedurl.ShortenedURL{}, destination{}, getWarningResponse(r, shortenedURL), false
+	}
+
+	// send the visitor to the destination of the first rule they match, otherwise split them between any variants
+	dest := destination{URL: shortenedURL.GetLong()}
+	if rule, ok := ruleservice.Evaluate(shortenedURL.GetRules(), getVisitor(opts, r)); ok {
+		dest.URL = rule.URL
+	} else if len(shortenedURL.GetVariants()) > 0 {
+		variant := chooseVariant(r, shortenedURL)
+		dest = destination{URL: variant.URL, Variant: variant.Name}
+	}
+
+	// follow any links to this shortener through to their final destination
+	longURL, err := resolveSelfLinks(repo, opts, r, shortenedURL.GetOwner(), dest.URL, shortenedURL.GetShort())
+	if errors.Is(err, errGuardedSelfLink) {
+		// send the visitor via the short URL, so that its own checks apply
+		longURL, err = dest.URL, nil
+	}
+	if err != nil {
+		logservice.FromContext(r.Context()).Warn("Failed to resolve self link", "shortCode", shortenedURL.GetShort(), "error", err)
+		return shortenedurl.ShortenedURL{}, destination{}, responseservice.NewErrResponse(err.Error(), http.StatusLoopDetected), false
+	}
+
+	// re-check the destination, in case it has been blocked since the link was created
+	if err := opts.DomainPolicy.Check(longURL); err != nil {
+		blockedTotal.Inc("redirect")
+		return shortenedurl.ShortenedURL{}, destination{}, responseservice.NewErrResponse(err.Error(), http.StatusUnavailableForLegalReasons).
+			WithErrorCode(responseservice.CodeBlockedDomain), false
+	}
+
+	dest.URL = longURL
+	return shortenedURL, dest, responseservice.JSONResponse{}, true
+}
+
+// GetHealth handles request to check that the process is alive
+func GetHealth(w http.ResponseWriter, r *http.Request) responseservice.JSONResponse {
+	return responseservice.NewOkResponse(map[string]string{
+		"health": "alive",
+	})
+}
+
+// GetReady handles request to check that the repository is reachable
+func GetReady(
+	repo repositoryinterface.RepositoryInterface,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	if err := repo.Ping(); err != nil {
+		logservice.FromContext(r.Context()).Warn("Repository is not ready", "backend", repo.Backend(), "error", err)
+		return responseservice.NewErrResponse(err.Error(), http.StatusServiceUnavailable)
+	}
+
+	return responseservice.NewOkResponse(map[string]string{
+		"ready": repo.Backend(),
+	})
+}
+
+// GetVersion handles request to describe the running build
+func GetVersion(
+	repo repositoryinterface.RepositoryInterface,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	return responseservice.NewOkResponse(map[string]string{
+		"version": versionservice.Version,
+		"commit":  versionservice.Commit,
+		"storage": repo.Backend(),
+	})
+}
+
+func getBaseURL(r *http.Request) string {
+	if r.TLS != nil {
+		return "https://" + r.Host
+	}
+
+	return "http://" + r.Host
+}
+
+// retrieveByShortCode retrieves a link by the canonical form of its short code, falling back to the short code as provided
+// for links that could not be migrated to canonical form
+func retrieveByShortCode(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	shortCode string,
+) (shortenedurl.ShortenedURL, error) {
+	canonical := opts.ShortCodes.Canonicalise(shortCode)
+
+	u, err := repo.RetrieveByShortCode(canonical)
+	if err != nil && canonical != shortCode {
+		return repo.RetrieveByShortCode(shortCode)
+	}
+
+	return u, err
+}
+
+// linkRequest represents the properties of a link supplied in a request body
+type linkRequest struct {
+	URL          string
+	Title        string
+	Interstitial bool
+	Passthrough  bool
+	Campaign     string
+	Rules        []shortenedurl.Rule
+	Variants     []shortenedurl.Variant
+
+	// Password is the password to protect the link with, or empty to remove protection. It is only applied if HasPassword is set
+	Password    string
+	HasPassword bool
+
+	// MaxClicks is the number of times the link may be followed, or 0 for any number. It is only applied if HasMaxClicks is set
+	MaxClicks    int
+	HasMaxClicks bool
+
+	// ActiveFrom and ActiveUntil bound when the link may be followed, either being zero if unbounded. They are only applied if HasActiveWindow is set
+	ActiveFrom      time.Time
+	ActiveUntil     time.Time
+	HasActiveWindow bool
 }
 
-func getValueOfURLFromRequestBody(r *http.Request) (string, error) {
+// getLinkFromRequestBody extracts a link from the request body, its URL normalised according to the provided policy
+func getLinkFromRequestBody(r *http.Request, policy urlpolicyservice.Policy) (linkRequest, error) {
 	// read request body
 	requestBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return "", err
+		return linkRequest{}, err
 	}
 
 	// parse request body as json
 	var jsonBody map[string]interface{}
 	err = json.Unmarshal(requestBody, &jsonBody)
 	if err != nil {
-		return "", err
+		return linkRequest{}, err
 	}
 
 	// check that url exists in payload and is a string
@@ -101,15 +439,122 @@ func getValueOfURLFromRequestBody(r *http.Request) (string, error) {
 	case string:
 		// ok
 	default:
-		return "", errors.New("`url` is a non-string or missing")
+		return linkRequest{}, invalidField("url", errors.New("`url` is a non-string or missing"))
+	}
+
+	// check optional properties
+	title, ok := jsonBody["title"].(string)
+	if !ok && jsonBody["title"] != nil {
+		return linkRequest{}, invalidField("title", errors.New("`title` is a non-string"))
+	}
+
+	interstitial, ok := jsonBody["interstitial"].(bool)
+	if !ok && jsonBody["interstitial"] != nil {
+		return linkRequest{}, invalidField("interstitial", errors.New("`interstitial` is a non-boolean"))
+	}
+
+	passthrough, ok := jsonBody["passthrough"].(bool)
+	if !ok && jsonBody["passthrough"] != nil {
+		return linkRequest{}, invalidField("passthrough", errors.New("`passthrough` is a non-boolean"))
+	}
+
+	password, ok := jsonBody["password"].(string)
+	if !ok && jsonBody["password"] != nil {
+		return linkRequest{}, invalidField("password", errors.New("`password` is a non-string"))
+	}
+
+	maxClicks, ok := jsonBody["maxClicks"].(float64)
+	if (!ok && jsonBody["maxClicks"] != nil) || maxClicks < 0 || maxClicks != float64(int(maxClicks)) {
+		return linkRequest{}, invalidField("maxClicks", errors.New("`maxClicks` is a non-integer or negative"))
+	}
+
+	activeFrom, err := getTimeFromRequestBody(jsonBody, "activeFrom")
+	if err != nil {
+		return linkRequest{}, err
+	}
+
+	activeUntil, err := getTimeFromRequestBody(jsonBody, "activeUntil")
+	if err != nil {
+		return linkRequest{}, err
+	}
+
+	if !activeFrom.IsZero() && !activeUntil.IsZero() && !activeUntil.After(activeFrom) {
+		return linkRequest{}, invalidField("activeUntil", errors.New("`activeUntil` is not after `activeFrom`"))
+	}
+
+	utm, err := getUTMFromRequestBody(jsonBody)
+	if err != nil {
+		return linkRequest{}, err
+	}
+
+	rules, err := getRulesFromRequestBody(jsonBody, policy)
+	if err != nil {
+		return linkRequest{}, err
 	}
 
-	// check that URL is valid
-	urlValue := jsonBody["url"].(string)
-	_, err = url.ParseRequestURI(urlValue)
+	variants, err := getVariantsFromRequestBody(jsonBody, policy)
 	if err != nil {
-		return "", errors.New("`url` is not a valid URL")
+		return linkRequest{}, err
+	}
+
+	// check that URL is valid, and normalise it so that equivalent URLs are deduplicated
+	urlValue, err := policy.Normalise(jsonBody["url"].(string))
+	if err != nil {
+		return linkRequest{}, invalidURL("url", err)
+	}
+
+	// merge campaign parameters into the URL, after normalising so that they aren't stripped as tracking parameters
+	if !utm.IsEmpty() {
+		if urlValue, err = utm.Apply(urlValue); err != nil {
+			return linkRequest{}, invalidURL("url", err)
+		}
+	}
+
+	return linkRequest{
+		URL:          urlValue,
+		Title:        strings.TrimSpace(title),
+		Interstitial: interstitial,
+		Passthrough:  passthrough,
+		Campaign:     campaignservice.CampaignOf(urlValue),
+		Rules:        rules,
+		Variants:     variants,
+		Password:     password,
+		HasPassword:  jsonBody["password"] != nil,
+		MaxClicks:    int(maxClicks),
+		HasMaxClicks: jsonBody["maxClicks"] != nil,
+
+		ActiveFrom:      activeFrom,
+		ActiveUntil:     activeUntil,
+		HasActiveWindow: jsonBody["activeFrom"] != nil || jsonBody["activeUntil"] != nil,
+	}, nil
+}
+
+// getUTMFromRequestBody extracts the optional `utm` object of campaign parameters from a parsed request body
+func getUTMFromRequestBody(jsonBody map[string]interface{}) (campaignservice.UTM, error) {
+	if jsonBody["utm"] == nil {
+		return campaignservice.UTM{}, nil
+	}
+
+	fields, ok := jsonBody["utm"].(map[string]interface{})
+	if !ok {
+		return campaignservice.UTM{}, invalidField("utm", errors.New("`utm` is a non-object"))
+	}
+
+	values := map[string]string{}
+	for _, name := range []string{"source", "medium", "campaign", "term", "content"} {
+		value, ok := fields[name].(string)
+		if !ok && fields[name] != nil {
+			return campaignservice.UTM{}, invalidField("utm."+name, fmt.Errorf("`utm.%s` is a non-string", name))
+		}
+
+		values[name] = strings.TrimSpace(value)
 	}
 
-	return urlValue, nil
+	return campaignservice.UTM{
+		Source:   values["source"],
+		Medium:   values["medium"],
+		Campaign: values["campaign"],
+		Term:     values["term"],
+		Content:  values["content"],
+	}, nil
 }
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetLinks handles request to list links, restricted to the principal's own links unless they are an admin
+func GetLinks(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	principal, ok := getPrincipal(opts, r)
+	if !ok {
+		return responseservice.NewErrResponse("Missing credentials", http.StatusUnauthorized)
+	}
+
+	// admins may list every link, or filter by owner
+	owner := principal.ID
+	if principal.IsAdmin() {
+		owner = r.URL.Query().Get("owner")
+	}
+
+	urls, err := repo.List(owner)
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to list shortened URLs", err, "owner", owner)
+	}
+
+	// optionally restrict to the links of a single campaign
+	campaign, filtered := r.URL.Query()["campaign"]
+
+	links := []map[string]interface{}{}
+	for _, u := range urls {
+		if filtered && u.GetCampaign() != campaign[0] {
+			continue
+		}
+
+		links = append(links, getLinkData(r, u))
+	}
+
+	return responseservice.NewOkResponse(map[string]interface{}{
+		"links": links,
+	})
+}
+
+// PutLink handles request to change the long URL that a link redirects to
+func PutLink(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	existing, errResponse, ok := getManageableLink(repo, opts, r)
+	if !ok {
+		return errResponse
+	}
+
+	// extract link properties from request body
+	link, err := getLinkFromRequestBody(r, opts.URLPolicy)
+	if err != nil {
+		return getRequestErrResponse(err)
+	}
+	urlValue := link.URL
+
+	// prevent links to this shortener, which could otherwise form chains or loops
+	urlValue, err = checkSelfLink(repo, opts, r, existing.GetOwner(), urlValue, existing.GetShort())
+	if err != nil {
+		return getRequestErrResponse(invalidURL("url", err))
+	}
+
+	// check that the destination is permitted
+	if err := opts.DomainPolicy.Check(urlValue); err != nil {
+		blockedTotal.Inc("shorten")
+		return getBlockedResponse("url", err)
+	}
+
+	// check that the destination is not a known threat
+	threat, errResponse, ok := checkThreat(opts, r, urlValue)
+	if !ok {
+		return errResponse
+	}
+
+	// check the destinations of any rules and variants in the same way
+	rules, ruleThreat, errResponse, ok := checkRules(repo, opts, r, existing.GetOwner(), link.Rules, existing.GetShort())
+	if !ok {
+		return errResponse
+	}
+
+	variants, variantThreat, errResponse, ok := checkVariants(repo, opts, r, existing.GetOwner(), link.Variants, existing.GetShort())
+	if !ok {
+		return errResponse
+	}
+
+	if threat == "" {
+		threat = ruleThreat
+	}
+
+	if threat == "" {
+		threat = variantThreat
+	}
+
+	// keep the link's existing password, unless a new one (or none) is provided
+	passwordHash := existing.GetPasswordHash()
+	if link.HasPassword {
+		if passwordHash, err = getPasswordHash(link); err != nil {
+			return getInternalErrResponse(r, "Failed to hash password", err)
+		}
+	}
+
+	// likewise keep the link's existing maximum clicks, unless a new one (or none) is provided
+	maxClicks := existing.GetMaxClicks()
+	if link.HasMaxClicks {
+		maxClicks = link.MaxClicks
+	}
+
+	// and its existing active window, unless a new one is provided
+	activeFrom, activeUntil := existing.GetActiveFrom(), existing.GetActiveUntil()
+	if link.HasActiveWindow {
+		activeFrom, activeUntil = link.ActiveFrom, link.ActiveUntil
+	}
+
+	updated, err := repo.Update(existing.
+		WithLong(urlValue).
+		WithThreat(threat).
+		WithTitle(link.Title).
+		WithInterstitial(link.Interstitial).
+		WithPassthrough(link.Passthrough).
+		WithCampaign(link.Campaign).
+		WithRules(rules).
+		WithVariants(variants).
+		WithPasswordHash(passwordHash).
+		WithMaxClicks(maxClicks).
+		WithActiveWindow(activeFrom, activeUntil))
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to update shortened URL", err, "shortCode", existing.GetShort())
+	}
+
+	return responseservice.NewOkResponse(getLinkData(r, updated))
+}
+
+// DeleteLink handles request to delete a link
+func DeleteLink(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	existing, errResponse, ok := getManageableLink(repo, opts, r)
+	if !ok {
+		return errResponse
+	}
+
+	if err := repo.Delete(existing.GetShort()); err != nil {
+		return getInternalErrResponse(r, "Failed to delete shortened URL", err, "shortCode", existing.GetShort())
+	}
+
+	return responseservice.NewEmptyResponse(http.StatusNoContent)
+}
+
+// getManageableLink retrieves the link identified by the request path, provided the principal may manage it
+func getManageableLink(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	r *http.Request,
+) (shortenedurl.ShortenedURL, responseservice.JSONResponse, bool) {
+	principal, ok := getPrincipal(opts, r)
+	if !ok {
+		return shortenedurl.ShortenedURL{}, responseservice.NewErrResponse("Missing credentials", http.StatusUnauthorized), false
+	}
+
+	shortCode := strings.TrimPrefix(r.URL.Path, "/api/links/")
+
+	existing, err := retrieveByShortCode(repo, opts, shortCode)
+	if err != nil {
+		return shortenedurl.ShortenedURL{}, responseservice.NewErrResponse("Shortened URL does not exist", http.StatusNotFound), false
+	}
+
+	if !principal.CanManage(existing.GetOwner()) {
+		// don't reveal whether other principals' links exist
+		return shortenedurl.ShortenedURL{}, responseservice.NewErrResponse("Shortened URL does not exist", http.StatusNotFound), false
+	}
+
+	return existing, responseservice.JSONResponse{}, true
+}
+
+func getLinkData(r *http.Request, u shortenedurl.ShortenedURL) map[string]interface{} {
+	data := map[string]interface{}{
+		"shortCode":    u.GetShort(),
+		"shortURL":     getBaseURL(r) + "/" + u.GetShort(),
+		"url":          u.GetLong(),
+		"owner":        u.GetOwner(),
+		"threat":       u.GetThreat(),
+		"title":        u.GetTitle(),
+		"clicks":       u.GetClicks(),
+		"interstitial": u.HasInterstitial(),
+		"passthrough":  u.HasPassthrough(),
+		"campaign":     u.GetCampaign(),
+		"rules":        getRulesData(u.GetRules()),
+		"variants":     getVariantsData(u.GetVariants()),
+		"protected":    u.IsProtected(),
+		"maxClicks":    u.GetMaxClicks(),
+	}
+
+	if !u.GetCreatedAt().IsZero() {
+		data["createdAt"] = u.GetCreatedAt().Format(time.RFC3339)
+	}
+
+	if !u.GetActiveFrom().IsZero() {
+		data["activeFrom"] = u.GetActiveFrom().Format(time.RFC3339)
+	}
+
+	if !u.GetActiveUntil().IsZero() {
+		data["activeUntil"] = u.GetActiveUntil().Format(time.RFC3339)
+	}
+
+	return data
+}
+
+// getRulesData describes a link's rules, omitting conditions that aren't set
+func getRulesData(rules []shortenedurl.Rule) []map[string]interface{} {
+	data := []map[string]interface{}{}
+	for _, rule := range rules {
+		ruleData := map[string]interface{}{"url": rule.URL}
+
+		for name, values := range map[string][]string{"platforms": rule.Platforms, "languages": rule.Languages, "countries": rule.Countries} {
+			if len(values) > 0 {
+				ruleData[name] = values
+			}
+		}
+
+		for name, value := range map[string]string{"from": rule.From, "until": rule.Until} {
+			if value != "" {
+				ruleData[name] = value
+			}
+		}
+
+		data = append(data, ruleData)
+	}
+
+	return data
+}
+
+// getVariantsData describes a link's variants, and how many times each has been followed
+func getVariantsData(variants []shortenedurl.Variant) []map[string]interface{} {
+	data := []map[string]interface{}{}
+	for _, v := range variants {
+		data = append(data, map[string]interface{}{
+			"name":   v.Name,
+			"url":    v.URL,
+			"weight": v.Weight,
+			"clicks": v.Clicks,
+		})
+	}
+
+	return data
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import "http-url-shortener/internal/services/metricsservice"
+
+var (
+	shortenedTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_shortened_total",
+		"Number of successful shorten requests, by whether the link was new or existing.",
+		"result",
+	)
+	redirectsTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_redirects_total",
+		"Number of short codes successfully redirected.",
+	)
+	notFoundTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_not_found_total",
+		"Number of redirect requests for short codes that do not exist.",
+	)
+	shortCodeCollisionsTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_short_code_collisions_total",
+		"Number of generated short codes that were retried as they already exist or are reserved.",
+	)
+	blockedTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_blocked_total",
+		"Number of requests refused as their destination is blocked, by whether shortening or redirecting.",
+		"stage",
+	)
+	threatsTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_threats_total",
+		"Number of destinations flagged as threats, by whether they were rejected or quarantined.",
+		"action",
+	)
+	exhaustedTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_exhausted_total",
+		"Number of redirect requests refused as the link has been followed as many times as it may be.",
+	)
+	inactiveTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_inactive_total",
+		"Number of redirect requests refused as they were outside the link's active window, by whether it was before or after.",
+		"window",
+	)
+	unlockAttemptsTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_unlock_attempts_total",
+		"Number of passwords entered for protected links, by whether they were correct, incorrect or rate limited.",
+		"result",
+	)
+)
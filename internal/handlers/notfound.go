@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"http-url-shortener/internal/services/pageservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+)
+
+// getNotFoundResponse returns the response for a short code that does not exist. Browsers are redirected to the configured
+// NotFoundURL if there is one, or otherwise shown a page saying so, whereas API clients are sent a JSON error
+func getNotFoundResponse(opts Options, r *http.Request) responseservice.JSONResponse {
+	if !acceptsHTML(r) {
+		return responseservice.NewErrResponse("Shortened URL does not exist", http.StatusNotFound)
+	}
+
+	if opts.NotFoundURL != "" {
+		return responseservice.NewEmptyResponse(http.StatusFound, "Location", opts.NotFoundURL, "Cache-Control", "no-store")
+	}
+
+	html, err := pageservice.RenderNotFound(pageservice.NotFoundPage{
+		URL:       getBaseURL(r) + r.URL.EscapedPath(),
+		SiteName:  opts.SiteName,
+		SearchURL: opts.NotFoundSearchURL,
+	})
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to render not found page", err)
+	}
+
+	return responseservice.NewHTMLResponse(html, http.StatusNotFound)
+}
+
+// acceptsHTML determines whether the request was made by a browser, rather than an API client, from its Accept header
+func acceptsHTML(r *http.Request) bool {
+	_, ok := responseservice.Negotiate(r.Header.Get("Accept")).(responseservice.HTMLRenderer)
+	return ok
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"http-url-shortener/internal/services/authservice"
+	"http-url-shortener/internal/services/domainpolicyservice"
+	"http-url-shortener/internal/services/geoipservice"
+	"http-url-shortener/internal/services/passwordservice"
+	"http-url-shortener/internal/services/ratelimitservice"
+	"http-url-shortener/internal/services/shortcodeservice"
+	"http-url-shortener/internal/services/urlcheckservice"
+	"http-url-shortener/internal/services/urlpolicyservice"
+	"net/http"
+	"time"
+)
+
+// Options represents configurable behaviour of the handlers
+type Options struct {
+	// AuthRequired determines whether requests must be made by an authenticated principal.
+	// When false, anonymous requests may manage every link
+	AuthRequired bool
+
+	// LinkQuota is the maximum number of links each non-admin principal may own, or 0 for no limit
+	LinkQuota int
+
+	// URLPolicy validates and normalises destination URLs
+	URLPolicy urlpolicyservice.Policy
+
+	// DomainPolicy determines the hosts that links may be created for, and continue to redirect to
+	DomainPolicy domainpolicyservice.Policy
+
+	// SelfHosts are hosts served by this shortener, in addition to the request's own host
+	SelfHosts []string
+
+	// ResolveSelfLinks determines whether a destination that links to this shortener is replaced by
+	// the final destination it redirects to. When false, such destinations are rejected
+	ResolveSelfLinks bool
+
+	// URLChecker flags destinations that are known threats, such as malware or phishing, or is nil to not check
+	URLChecker urlcheckservice.URLChecker
+
+	// QuarantineThreats determines whether flagged destinations are shortened but quarantined behind a warning page,
+	// rather than rejected
+	QuarantineThreats bool
+
+	// ParamConflict determines which value wins when a passed through query parameter is already on the destination:
+	// ParamConflictDestination (the default), ParamConflictRequest, or ParamConflictAppend to keep both
+	ParamConflict string
+
+	// ShortCodes canonicalises short codes when they are generated and looked up
+	ShortCodes shortcodeservice.Canonicaliser
+
+	// GeoIP locates the country of visitors, for rules that match on country, or is nil to not locate visitors
+	GeoIP geoipservice.Locator
+
+	// TrustProxy determines whether a visitor's IP address is taken from the X-Forwarded-For header
+	TrustProxy bool
+
+	// Unlocker signs the cookies that let visitors follow a password protected link once they have entered its password
+	Unlocker passwordservice.Unlocker
+
+	// UnlockLimiter limits how often each visitor may guess the password of a protected link, or is nil for no limit
+	UnlockLimiter *ratelimitservice.Limiter
+
+	// InactiveURL is where visitors are sent when following a link outside of its active window,
+	// or is empty to show them a page explaining that the link is not yet, or no longer, available
+	InactiveURL string
+
+	// NotFoundURL is where visitors are redirected when following a short code that does not exist,
+	// or is empty to show them a page saying so. API clients are always sent a JSON error instead
+	NotFoundURL string
+
+	// NotFoundSearchURL is where the search box on the not found page submits its `q` parameter to, or is empty for no search box
+	NotFoundSearchURL string
+
+	// SiteName is the name that the not found page is branded with
+	SiteName string
+
+	// RedirectMaxAge is how long clients may cache the permanent redirect of a link that always redirects to the same
+	// destination, before checking again for a changed destination or domain policy
+	RedirectMaxAge time.Duration
+
+	// Clock returns the current time, or is nil to use the system clock
+	Clock func() time.Time
+}
+
+// now returns the current time according to the configured clock
+func now(opts Options) time.Time {
+	if opts.Clock != nil {
+		return opts.Clock()
+	}
+
+	return time.Now()
+}
+
+// getPrincipal returns the principal making the request, and whether the request may proceed
+func getPrincipal(opts Options, r *http.Request) (authservice.Principal, bool) {
+	if p, ok := authservice.PrincipalFromContext(r.Context()); ok {
+		return p, true
+	}
+
+	if !opts.AuthRequired {
+		return authservice.Principal{Scopes: []string{"admin"}}, true
+	}
+
+	return authservice.Principal{}, false
+}
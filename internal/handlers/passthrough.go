@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// query parameter conflict rules, for when a passed through parameter is already on the destination
+const (
+	ParamConflictDestination = "destination"
+	ParamConflictRequest     = "request"
+	ParamConflictAppend      = "append"
+)
+
+// splitPath splits an escaped request path into its first segment (the short code) and any remaining segments
+func splitPath(escapedPath string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(escapedPath, "/"), "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// passThrough appends extra path segments and merges query parameters from a request into a link's destination
+func passThrough(longURL string, extraPath string, rawQuery string, conflict string) (string, error) {
+	u, err := url.Parse(longURL)
+	if err != nil {
+		return "", err
+	}
+
+	if extraPath != "" {
+		// don't allow passed through segments to escape the destination's path
+		for _, segment := range strings.Split(extraPath, "/") {
+			if s, _ := url.PathUnescape(segment); s == "." || s == ".." {
+				return "", errors.New("Path must not contain relative segments")
+			}
+		}
+
+		escaped := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + extraPath
+		if u.Path, err = url.PathUnescape(escaped); err != nil {
+			return "", err
+		}
+		u.RawPath = escaped
+	}
+
+	u.RawQuery = mergeQuery(u.RawQuery, rawQuery, conflict)
+
+	return u.String(), nil
+}
+
+// mergeQuery merges the raw pairs of two query strings, preserving their order and encoding
+func mergeQuery(destination string, request string, conflict string) string {
+	destinationPairs := splitQuery(destination)
+	requestPairs := splitQuery(request)
+
+	var kept []string
+	switch conflict {
+	case ParamConflictRequest:
+		// request values replace destination values
+		kept = append(withoutKeys(destinationPairs, queryKeys(requestPairs)), requestPairs...)
+	case ParamConflictAppend:
+		kept = append(destinationPairs, requestPairs...)
+	default:
+		kept = append(destinationPairs, withoutKeys(requestPairs, queryKeys(destinationPairs))...)
+	}
+
+	return strings.Join(kept, "&")
+}
+
+func splitQuery(rawQuery string) []string {
+	pairs := []string{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs
+}
+
+func queryKey(pair string) string {
+	key, _ := url.QueryUnescape(strings.SplitN(pair, "=", 2)[0])
+	return key
+}
+
+func queryKeys(pairs []string) map[string]bool {
+	keys := map[string]bool{}
+	for _, pair := range pairs {
+		keys[queryKey(pair)] = true
+	}
+
+	return keys
+}
+
+func withoutKeys(pairs []string, keys map[string]bool) []string {
+	kept := []string{}
+	for _, pair := range pairs {
+		if !keys[queryKey(pair)] {
+			kept = append(kept, pair)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/services/pageservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+)
+
+// getPreviewResponse returns the page that describes where a link goes, in place of redirecting to it
+func getPreviewResponse(r *http.Request, u shortenedurl.ShortenedURL, longURL string) responseservice.JSONResponse {
+	html, err := pageservice.RenderPreview(pageservice.PreviewPage{
+		ShortURL:     getBaseURL(r) + "/" + u.GetShort(),
+		Destination:  longURL,
+		Title:        u.GetTitle(),
+		CreatedAt:    u.GetCreatedAt(),
+		Clicks:       u.GetClicks(),
+		Interstitial: u.HasInterstitial(),
+	})
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to render preview page", err)
+	}
+
+	return responseservice.NewHTMLResponse(html, http.StatusOK, "Cache-Control", "no-store")
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/netservice"
+	"http-url-shortener/internal/services/pageservice"
+	"http-url-shortener/internal/services/passwordservice"
+	"http-url-shortener/internal/services/responseservice"
+	"http-url-shortener/internal/services/shortcodeservice"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PasswordHeader carries the password of a protected link for API clients, in place of submitting the password form
+const PasswordHeader = "X-Link-Password"
+
+// PostShortURLUnlock handles request to unlock a password protected link, submitted from its password form.
+// Once unlocked, the visitor is sent back to the short URL with a cookie that lets them follow it until the cookie expires
+func PostShortURLUnlock(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	w http.ResponseWriter,
+	r *http.Request,
+) responseservice.JSONResponse {
+	shortCode, _ := splitPath(r.URL.EscapedPath())
+	shortCode = strings.TrimSuffix(shortCode, "+")
+
+	// only protected links may be posted to, so other short codes are not distinguished from each other
+	if shortCode == "" || shortcodeservice.IsReserved(shortCode) {
+		return responseservice.NewErrResponse("Method not allowed", http.StatusMethodNotAllowed)
+	}
+
+	shortenedURL, err := retrieveByShortCode(repo, opts, shortCode)
+	if err != nil || !shortenedURL.IsProtected() {
+		return responseservice.NewErrResponse("Method not allowed", http.StatusMethodNotAllowed)
+	}
+
+	code, message, headers := attemptUnlock(opts, r, shortenedURL, r.PostFormValue("password"))
+	if code != 0 {
+		return getPasswordResponse(r, shortenedURL, message, code).WithHeaders(headers...)
+	}
+
+	return responseservice.NewEmptyResponse(
+		http.StatusSeeOther,
+		"Location",
+		r.URL.RequestURI(),
+		"Set-Cookie",
+		getUnlockCookie(opts, r, shortenedURL).String(),
+	)
+}
+
+// checkUnlocked determines whether the visitor may follow a link, either as it isn't protected, they have previously unlocked it,
+// or they have provided its password in the PasswordHeader. Otherwise the password form, or an error for API clients, is returned
+func checkUnlocked(opts Options, r *http.Request, u shortenedurl.ShortenedURL) (responseservice.JSONResponse, bool) {
+	if !u.IsProtected() {
+		return responseservice.JSONResponse{}, true
+	}
+
+	if cookie, err := r.Cookie(unlockCookieName(u)); err == nil {
+		if opts.Unlocker.Valid(cookie.Value, u.GetShort(), u.GetPasswordHash(), now(opts)) {
+			return responseservice.JSONResponse{}, true
+		}
+	}
+
+	if password := r.Header.Get(PasswordHeader); password != "" {
+		code, message, headers := attemptUnlock(opts, r, u, password)
+		if code == http.StatusUnauthorized {
+			return responseservice.NewErrResponse(message, code).WithErrorCode(responseservice.CodeIncorrectPassword), false
+		}
+
+		if code != 0 {
+			return responseservice.NewErrResponse(message, code).WithHeaders(headers...), false
+		}
+
+		return responseservice.JSONResponse{}, true
+	}
+
+	return getPasswordResponse(r, u, "", http.StatusUnauthorized), false
+}
+
+// attemptUnlock checks a password entered for a protected link, limiting how often each visitor may guess it.
+// It returns a status code of 0 if the password is correct, otherwise the status code, message and headers of the failure
+func attemptUnlock(opts Options, r *http.Request, u shortenedurl.ShortenedURL, password string) (int, string, []string) {
+	if opts.UnlockLimiter != nil {
+		res := opts.UnlockLimiter.Allow(netservice.ClientIP(r, opts.TrustProxy) + " " + u.GetShort())
+		if !res.Allowed {
+			unlockAttemptsTotal.Inc("limited")
+			retryAfter := strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds())))
+			return http.StatusTooManyRequests, "Too many password attempts, try again in " + retryAfter + " seconds", []string{"Retry-After", retryAfter}
+		}
+	}
+
+	if !passwordservice.Verify(password, u.GetPasswordHash()) {
+		unlockAttemptsTotal.Inc("failure")
+		return http.StatusUnauthorized, "Incorrect password", nil
+	}
+
+	unlockAttemptsTotal.Inc("success")
+	return 0, "", nil
+}
+
+// getPasswordHash hashes the password that a link is to be protected with, or returns an empty hash if it is not to be protected
+func getPasswordHash(link linkRequest) (string, error) {
+	if link.Password == "" {
+		return "", nil
+	}
+
+	return passwordservice.Hash(link.Password)
+}
+
+// getPasswordResponse returns the page that asks for the password of a protected link, optionally explaining why an attempt failed
+func getPasswordResponse(r *http.Request, u shortenedurl.ShortenedURL, message string, code int) responseservice.JSONResponse {
+	html, err := pageservice.RenderPassword(pageservice.PasswordPage{
+		ShortURL: getBaseURL(r) + "/" + u.GetShort(),
+		Action:   r.URL.RequestURI(),
+		Error:    message,
+	})
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to render password page", err)
+	}
+
+	return responseservice.NewHTMLResponse(html, code, "Cache-Control", "no-store")
+}
+
+// getUnlockCookie returns the signed, short-lived cookie that lets the visitor follow a protected link without re-entering its password
+func getUnlockCookie(opts Options, r *http.Request, u shortenedurl.ShortenedURL) *http.Cookie {
+	return &http.Cookie{
+		Name:     unlockCookieName(u),
+		Value:    opts.Unlocker.Token(u.GetShort(), u.GetPasswordHash(), now(opts)),
+		Path:     "/",
+		MaxAge:   int(opts.Unlocker.TTL.Seconds()),
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func unlockCookieName(u shortenedurl.ShortenedURL) string {
+	return "unlock_" + u.GetShort()
+}
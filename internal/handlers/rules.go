@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/netservice"
+	"http-url-shortener/internal/services/responseservice"
+	"http-url-shortener/internal/services/ruleservice"
+	"http-url-shortener/internal/services/urlpolicyservice"
+	"net/http"
+)
+
+// getRulesFromRequestBody extracts the optional ordered `rules` from a parsed request body, their URLs normalised according to the provided policy
+func getRulesFromRequestBody(jsonBody map[string]interface{}, policy urlpolicyservice.Policy) ([]shortenedurl.Rule, error) {
+	if jsonBody["rules"] == nil {
+		return nil, nil
+	}
+
+	items, ok := jsonBody["rules"].([]interface{})
+	if !ok {
+		return nil, invalidField("rules", errors.New("`rules` is a non-array"))
+	}
+
+	rules := []shortenedurl.Rule{}
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, invalidField(fmt.Sprintf("rules[%d]", i), fmt.Errorf("`rules[%d]` is a non-object", i))
+		}
+
+		urlValue, ok := fields["url"].(string)
+		if !ok {
+			return nil, invalidField(fmt.Sprintf("rules[%d].url", i), fmt.Errorf("`rules[%d].url` is a non-string or missing", i))
+		}
+
+		rule := shortenedurl.Rule{}
+		for name, target := range map[string]*[]string{"platforms": &rule.Platforms, "languages": &rule.Languages, "countries": &rule.Countries} {
+			values, err := getStrings(fields[name])
+			if err != nil {
+				return nil, invalidField(fmt.Sprintf("rules[%d].%s", i, name), fmt.Errorf("`rules[%d].%s` is a non-array of strings", i, name))
+			}
+			*target = values
+		}
+
+		for name, target := range map[string]*string{"from": &rule.From, "until": &rule.Until} {
+			value, ok := fields[name].(string)
+			if !ok && fields[name] != nil {
+				return nil, invalidField(fmt.Sprintf("rules[%d].%s", i, name), fmt.Errorf("`rules[%d].%s` is a non-string", i, name))
+			}
+			*target = value
+		}
+
+		if err := ruleservice.Validate(rule); err != nil {
+			return nil, invalidField(fmt.Sprintf("rules[%d]", i), fmt.Errorf("`rules[%d]`: %s", i, err.Error()))
+		}
+
+		normalised, err := policy.Normalise(urlValue)
+		if err != nil {
+			return nil, invalidURL(fmt.Sprintf("rules[%d].url", i), fmt.Errorf("`rules[%d]`: %s", i, err.Error()))
+		}
+		rule.URL = normalised
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// getStrings converts an optional JSON array of strings
+func getStrings(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("Not an array")
+	}
+
+	values := []string{}
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("Not a string")
+		}
+		values = append(values, s)
+	}
+
+	return values, nil
+}
+
+// checkRules applies the same checks to the destination of each rule as to a link's default destination, returning
+// the checked rules and the threat that any of them has been flagged as. seen holds short codes that rule destinations
+// must not link back to, such as the link being updated
+func checkRules(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	r *http.Request,
+	owner string,
+	rules []shortenedurl.Rule,
+	seen ...string,
+) ([]shortenedurl.Rule, string, responseservice.JSONResponse, bool) {
+	var checked []shortenedurl.Rule
+	var flagged string
+
+	for i, rule := range rules {
+		urlValue, threat, errResponse, ok := checkDestination(repo, opts, r, owner, fmt.Sprintf("rules[%d]", i), rule.URL, seen...)
+		if !ok {
+			return nil, "", errResponse, false
+		}
+
+		if flagged == "" {
+			flagged = threat
+		}
+
+		rule.URL = urlValue
+		checked = append(checked, rule)
+	}
+
+	return checked, flagged, responseservice.JSONResponse{}, true
+}
+
+// checkDestination applies the same checks to an additional destination of a link, such as that of a rule or variant,
+// as to its default destination. Errors are prefixed with the field (e.g. `rules[0]`) that the destination was provided in
+func checkDestination(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	r *http.Request,
+	owner string,
+	field string,
+	urlValue string,
+	seen ...string,
+) (string, string, responseservice.JSONResponse, bool) {
+	urlValue, err := checkSelfLink(repo, opts, r, owner, urlValue, seen...)
+	if err != nil {
+		return "", "", getRequestErrResponse(invalidURL(field+".url", fmt.Errorf("`%s`: %s", field, err.Error()))), false
+	}
+
+	if err := opts.DomainPolicy.Check(urlValue); err != nil {
+		blockedTotal.Inc("shorten")
+		return "", "", getBlockedResponse(field+".url", fmt.Errorf("`%s`: %s", field, err.Error())), false
+	}
+
+	// a flagged additional destination quarantines the whole link, as visitors can't choose which destination they're sent to
+	threat, errResponse, ok := checkThreat(opts, r, urlValue)
+	if !ok {
+		return "", "", errResponse, false
+	}
+
+	return urlValue, threat, responseservice.JSONResponse{}, true
+}
+
+// getVisitor determines the properties of a request that a link's rules are matched against
+func getVisitor(opts Options, r *http.Request) ruleservice.Visitor {
+	v := ruleservice.Visitor{
+		Platform: ruleservice.Platform(r.UserAgent()),
+		Language: ruleservice.Language(r.Header.Get("Accept-Language")),
+		Time:     now(opts),
+	}
+
+	if opts.GeoIP != nil {
+		v.Country = opts.GeoIP.Country(netservice.ClientIP(r, opts.TrustProxy))
+	}
+
+	return v
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/services/pageservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+	"time"
+)
+
+// getTimeFromRequestBody extracts an optional RFC 3339 time from a parsed request body, which is zero if missing or empty
+func getTimeFromRequestBody(jsonBody map[string]interface{}, name string) (time.Time, error) {
+	value, ok := jsonBody[name].(string)
+	if !ok && jsonBody[name] != nil {
+		return time.Time{}, invalidField(name, fmt.Errorf("`%s` is a non-string", name))
+	}
+
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, invalidField(name, fmt.Errorf("`%s` is not an RFC 3339 time", name))
+	}
+
+	return t.UTC(), nil
+}
+
+// getInactiveResponse returns the response for a link followed outside of its active window,
+// which redirects to the configured InactiveURL if there is one, or otherwise explains when the link is available
+func getInactiveResponse(opts Options, r *http.Request, u shortenedurl.ShortenedURL) responseservice.JSONResponse {
+	upcoming := !u.GetActiveFrom().IsZero() && now(opts).Before(u.GetActiveFrom())
+	if upcoming {
+		inactiveTotal.Inc("before")
+	} else {
+		inactiveTotal.Inc("after")
+	}
+
+	if opts.InactiveURL != "" {
+		return responseservice.NewEmptyResponse(http.StatusFound, "Location", opts.InactiveURL, "Cache-Control", "no-store")
+	}
+
+	html, err := pageservice.RenderInactive(pageservice.InactivePage{
+		ShortURL:    getBaseURL(r) + "/" + u.GetShort(),
+		ActiveFrom:  u.GetActiveFrom(),
+		ActiveUntil: u.GetActiveUntil(),
+		Upcoming:    upcoming,
+	})
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to render inactive page", err)
+	}
+
+	// a link that hasn't launched yet may still be found later, whereas an expired link is gone for good
+	code := http.StatusGone
+	if upcoming {
+		code = http.StatusNotFound
+	}
+
+	return responseservice.NewHTMLResponse(html, code, "Cache-Control", "no-store")
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxSelfLinkHops is the longest chain of short URLs that will be followed when resolving a self link
+const maxSelfLinkHops = 10
+
+// errGuardedSelfLink is returned when a self link's chain passes through a short URL that must be visited itself
+var errGuardedSelfLink = errors.New("Destination links to a short URL that is protected, limited, scheduled, quarantined, has rules or variants, or is owned by another principal")
+
+// resolveSelfLinks follows a destination that links to this shortener through to its final, external destination,
+// on behalf of the owner of the linking link. seen holds short codes already part of the chain, such as the link being redirected
+func resolveSelfLinks(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	r *http.Request,
+	owner string,
+	longURL string,
+	seen ...string,
+) (string, error) {
+	visited := map[string]bool{}
+	for _, s := range seen {
+		visited[s] = true
+	}
+
+	for hops := 0; hops <= maxSelfLinkHops; hops++ {
+		u, err := url.Parse(longURL)
+		if err != nil || !isSelfHost(opts, r, u.Host) {
+			return longURL, nil
+		}
+
+		shortCode, _ := splitPath(u.EscapedPath())
+
+		link, err := retrieveByShortCode(repo, opts, shortCode)
+		if err != nil {
+			return "", errors.New("Destination links to this shortener, but not to an existing short URL")
+		}
+
+		if visited[link.GetShort()] {
+			return "", errors.New("Destination forms a redirect loop")
+		}
+		visited[link.GetShort()] = true
+
+		// following the link's long URL would skip its own checks, and reveal another principal's destination
+		if isGuarded(link, owner) {
+			return "", errGuardedSelfLink
+		}
+
+		longURL = link.GetLong()
+	}
+
+	return "", errors.New("Destination redirects too many times")
+}
+
+// checkSelfLink rejects a destination that links to this shortener or, if configured to, resolves it to its final destination
+func checkSelfLink(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	r *http.Request,
+	owner string,
+	longURL string,
+	seen ...string,
+) (string, error) {
+	u, err := url.Parse(longURL)
+	if err != nil || !isSelfHost(opts, r, u.Host) {
+		return longURL, nil
+	}
+
+	if !opts.ResolveSelfLinks {
+		return "", errors.New("Destination must not link to this shortener")
+	}
+
+	return resolveSelfLinks(repo, opts, r, owner, longURL, seen...)
+}
+
+// isGuarded determines whether a link must be visited itself, rather than resolved on behalf of the provided owner
+func isGuarded(link shortenedurl.ShortenedURL, owner string) bool {
+	return link.GetOwner() != owner || !link.IsReusable() || link.IsQuarantined()
+}
+
+// isSelfHost determines whether the provided host (and optional port) is served by this shortener
+func isSelfHost(opts Options, r *http.Request, host string) bool {
+	host = withoutDefaultPort(strings.ToLower(host))
+
+	if host == withoutDefaultPort(strings.ToLower(r.Host)) {
+		return true
+	}
+
+	for _, h := range opts.SelfHosts {
+		h = withoutDefaultPort(strings.ToLower(h))
+
+		// a configured host without a port matches any port
+		if h == host || (!strings.Contains(h, ":") && strings.HasPrefix(host, h+":")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func withoutDefaultPort(host string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(host, ":80"), ":443")
+}
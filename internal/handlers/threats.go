@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/services/logservice"
+	"http-url-shortener/internal/services/pageservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+)
+
+// checkThreat consults the URL checker (if any) about a destination, returning the threat type that a quarantined link should be flagged with.
+// If the destination is rejected, an error response is returned instead
+func checkThreat(opts Options, r *http.Request, longURL string) (string, responseservice.JSONResponse, bool) {
+	if opts.URLChecker == nil {
+		return "", responseservice.JSONResponse{}, true
+	}
+
+	threat, err := opts.URLChecker.Check(longURL)
+	if err != nil {
+		logservice.FromContext(r.Context()).Error("Failed to check destination", "url", longURL, "error", err)
+		return "", responseservice.NewErrResponse("Unable to check destination", http.StatusServiceUnavailable), false
+	}
+
+	if threat == "" {
+		return "", responseservice.JSONResponse{}, true
+	}
+
+	if !opts.QuarantineThreats {
+		threatsTotal.Inc("rejected")
+		return "", responseservice.NewErrResponse(
+			fmt.Sprintf("Destination has been flagged as %s", threat),
+			http.StatusForbidden,
+		).WithErrorCode(responseservice.CodeThreatDetected), false
+	}
+
+	threatsTotal.Inc("quarantined")
+	return threat, responseservice.JSONResponse{}, true
+}
+
+// getWarningResponse returns the interstitial page shown in place of redirecting to a quarantined link
+func getWarningResponse(r *http.Request, u shortenedurl.ShortenedURL) responseservice.JSONResponse {
+	html, err := pageservice.RenderWarning(pageservice.WarningPage{
+		ShortURL:    getBaseURL(r) + "/" + u.GetShort(),
+		Destination: u.GetLong(),
+		Threat:      u.GetThreat(),
+	})
+	if err != nil {
+		return getInternalErrResponse(r, "Failed to render warning page", err)
+	}
+
+	return responseservice.NewHTMLResponse(html, http.StatusForbidden, "Cache-Control", "no-store")
+}
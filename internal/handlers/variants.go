@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/responseservice"
+	"http-url-shortener/internal/services/urlpolicyservice"
+	"math/rand"
+	"net/http"
+)
+
+// variantCookieMaxAge is how long, in seconds, a visitor remains assigned to the same variant of a link
+const variantCookieMaxAge = 90 * 24 * 60 * 60
+
+// getVariantsFromRequestBody extracts the optional weighted `variants` from a parsed request body, their URLs normalised
+// according to the provided policy. Variants without a name are named `a`, `b`, `c` etc. by their position
+func getVariantsFromRequestBody(jsonBody map[string]interface{}, policy urlpolicyservice.Policy) ([]shortenedurl.Variant, error) {
+	if jsonBody["variants"] == nil {
+		return nil, nil
+	}
+
+	items, ok := jsonBody["variants"].([]interface{})
+	if !ok {
+		return nil, invalidField("variants", errors.New("`variants` is a non-array"))
+	}
+
+	variants := []shortenedurl.Variant{}
+	names := map[string]bool{}
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, invalidField(fmt.Sprintf("variants[%d]", i), fmt.Errorf("`variants[%d]` is a non-object", i))
+		}
+
+		urlValue, ok := fields["url"].(string)
+		if !ok {
+			return nil, invalidField(fmt.Sprintf("variants[%d].url", i), fmt.Errorf("`variants[%d].url` is a non-string or missing", i))
+		}
+
+		weight, ok := fields["weight"].(float64)
+		if fields["weight"] == nil {
+			weight, ok = 1, true
+		}
+		if !ok || weight < 1 || weight != float64(int(weight)) {
+			return nil, invalidField(fmt.Sprintf("variants[%d].weight", i), fmt.Errorf("`variants[%d].weight` is not a positive integer", i))
+		}
+
+		name, ok := fields["name"].(string)
+		if !ok && fields["name"] != nil {
+			return nil, invalidField(fmt.Sprintf("variants[%d].name", i), fmt.Errorf("`variants[%d].name` is a non-string", i))
+		}
+		if name == "" {
+			name = string(rune('a' + i%26))
+		}
+		if names[name] {
+			return nil, invalidField(fmt.Sprintf("variants[%d].name", i), fmt.Errorf("`variants[%d].name` `%s` is not unique", i, name))
+		}
+		names[name] = true
+
+		normalised, err := policy.Normalise(urlValue)
+		if err != nil {
+			return nil, invalidURL(fmt.Sprintf("variants[%d].url", i), fmt.Errorf("`variants[%d]`: %s", i, err.Error()))
+		}
+
+		variants = append(variants, shortenedurl.Variant{Name: name, URL: normalised, Weight: int(weight)})
+	}
+
+	return variants, nil
+}
+
+// checkVariants applies the same checks to the destination of each variant as to a link's default destination, returning
+// the checked variants and the threat that any of them has been flagged as
+func checkVariants(
+	repo repositoryinterface.RepositoryInterface,
+	opts Options,
+	r *http.Request,
+	owner string,
+	variants []shortenedurl.Variant,
+	seen ...string,
+) ([]shortenedurl.Variant, string, responseservice.JSONResponse, bool) {
+	var checked []shortenedurl.Variant
+	var flagged string
+
+	for i, variant := range variants {
+		urlValue, threat, errResponse, ok := checkDestination(repo, opts, r, owner, fmt.Sprintf("variants[%d]", i), variant.URL, seen...)
+		if !ok {
+			return nil, "", errResponse, false
+		}
+
+		if flagged == "" {
+			flagged = threat
+		}
+
+		variant.URL = urlValue
+		checked = append(checked, variant)
+	}
+
+	return checked, flagged, responseservice.JSONResponse{}, true
+}
+
+// chooseVariant returns the variant that the visitor has previously been assigned to, or otherwise assigns one at random
+// in proportion to the variants' weights
+func chooseVariant(r *http.Request, u shortenedurl.ShortenedURL) shortenedurl.Variant {
+	variants := u.GetVariants()
+
+	if cookie, err := r.Cookie(variantCookieName(u)); err == nil {
+		for _, v := range variants {
+			if v.Name == cookie.Value {
+				return v
+			}
+		}
+	}
+
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	n := rand.Intn(total)
+	for _, v := range variants {
+		if n < v.Weight {
+			return v
+		}
+		n -= v.Weight
+	}
+
+	return variants[len(variants)-1]
+}
+
+// getVariantCookie returns the cookie that keeps the visitor assigned to the same variant of a link
+func getVariantCookie(u shortenedurl.ShortenedURL, variant string) *http.Cookie {
+	return &http.Cookie{
+		Name:     variantCookieName(u),
+		Value:    variant,
+		Path:     "/",
+		MaxAge:   variantCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func variantCookieName(u shortenedurl.ShortenedURL) string {
+	return "variant_" + u.GetShort()
+}
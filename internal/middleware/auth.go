@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"fmt"
+	"http-url-shortener/internal/entities/apikey"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/apikeyservice"
+	"http-url-shortener/internal/services/authservice"
+	"http-url-shortener/internal/services/logservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+	"strings"
+)
+
+// APIKeyAuth authenticates requests bearing an X-API-Key header, attaching the key's principal to the request context
+func APIKeyAuth(repo repositoryinterface.APIKeyRepositoryInterface, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if secret := r.Header.Get("X-API-Key"); secret != "" {
+			p, err := apikeyservice.Authenticate(repo, secret)
+			if err != nil {
+				writeError(w, r, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			r = r.WithContext(authservice.WithPrincipal(r.Context(), p))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BearerAuth authenticates requests bearing an `Authorization: Bearer` token, attaching the token's principal to the request context
+func BearerAuth(authenticator TokenAuthenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+			p, err := authenticator.Authenticate(strings.TrimSpace(header[7:]))
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeError(w, r, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			r = r.WithContext(authservice.WithPrincipal(r.Context(), p))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TokenAuthenticator resolves a bearer token to the principal it identifies
+type TokenAuthenticator interface {
+	Authenticate(token string) (authservice.Principal, error)
+}
+
+// RequireScope requires an authenticated principal granted the relevant scope for every mutating or statistics /api/ route
+func RequireScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := requiredScope(r)
+		if scope == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, ok := authservice.PrincipalFromContext(r.Context())
+		if !ok {
+			writeError(w, r, "Missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		if !p.HasScope(scope) && !p.IsAdmin() {
+			writeError(w, r, fmt.Sprintf("Principal lacks `%s` scope", scope), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requiredScope returns the scope required to perform the request, or an empty string if it is neither mutating
+// nor reports statistics
+func requiredScope(r *http.Request) string {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return ""
+	}
+
+	// listing links and campaigns reports their clicks
+	if r.URL.Path == "/api/links" || r.URL.Path == "/api/campaigns" {
+		return apikey.ScopeReadStats
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		return apikey.ScopeCreate
+	case http.MethodPut, http.MethodPatch:
+		return apikey.ScopeUpdate
+	case http.MethodDelete:
+		return apikey.ScopeDelete
+	}
+
+	return ""
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, message string, code int) {
+	responseservice.NewErrResponse(message, code).
+		WithRequestID(logservice.RequestID(r.Context())).
+		WriteNegotiated(w, r)
+}
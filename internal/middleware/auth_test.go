@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"errors"
+	"http-url-shortener/internal/entities/apikey"
+	"http-url-shortener/internal/repositories/apikeyfilesystemrepository"
+	"http-url-shortener/internal/services/apikeyservice"
+	"http-url-shortener/internal/services/authservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestItRejectsAMutatingRequestWithoutAnAPIKey(t *testing.T) {
+	repo := getTestKeyRepository()
+	defer clearTestKeyData()
+
+	w := serveWithAPIKey(repo, "POST", "/api/shorten", "")
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusUnauthorized, w.Code)
+	}
+
+	json := responseservice.ParseJSON(w.Result())
+	if json["data"].(map[string]interface{})["message"] != "Missing credentials" {
+		t.Errorf("Expected message of '%s', instead received '%+v'", "Missing credentials", json["data"])
+	}
+}
+
+func TestItRejectsAnInvalidAPIKey(t *testing.T) {
+	repo := getTestKeyRepository()
+	defer clearTestKeyData()
+
+	w := serveWithAPIKey(repo, "GET", "/ABC1", "hus_unknown")
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestItRejectsAnAPIKeyWithoutTheRequiredScope(t *testing.T) {
+	repo := getTestKeyRepository()
+	defer clearTestKeyData()
+
+	secret, _, _ := apikeyservice.Mint(repo, "analyst", []string{apikey.ScopeReadStats})
+
+	w := serveWithAPIKey(repo, "POST", "/api/shorten", secret)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestItRequiresTheReadStatsScopeToListLinksAndCampaigns(t *testing.T) {
+	repo := getTestKeyRepository()
+	defer clearTestKeyData()
+
+	creator, _, _ := apikeyservice.Mint(repo, "marketing", []string{apikey.ScopeCreate})
+	analyst, _, _ := apikeyservice.Mint(repo, "analyst", []string{apikey.ScopeReadStats})
+
+	for _, path := range []string{"/api/links", "/api/campaigns"} {
+		if w := serveWithAPIKey(repo, "GET", path, creator); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d for %s, instead received %d", http.StatusForbidden, path, w.Code)
+		}
+
+		if w := serveWithAPIKey(repo, "GET", path, analyst); w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d for %s, instead received %d", http.StatusOK, path, w.Code)
+		}
+	}
+}
+
+func TestItAllowsAnAPIKeyWithTheRequiredScope(t *testing.T) {
+	repo := getTestKeyRepository()
+	defer clearTestKeyData()
+
+	secret, k, _ := apikeyservice.Mint(repo, "marketing", []string{apikey.ScopeCreate})
+
+	var principal authservice.Principal
+	handler := APIKeyAuth(repo, RequireScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = authservice.PrincipalFromContext(r.Context())
+	})))
+
+	r := httptest.NewRequest("POST", "http://localhost:8080/api/shorten", nil)
+	r.Header.Set("X-API-Key", secret)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusOK, w.Code)
+	}
+
+	if principal.ID != k.GetID() {
+		t.Errorf("Expected principal '%s', instead received '%+v'", k.GetID(), principal)
+	}
+}
+
+func TestItAllowsANonMutatingRequestWithoutAnAPIKey(t *testing.T) {
+	repo := getTestKeyRepository()
+	defer clearTestKeyData()
+
+	w := serveWithAPIKey(repo, "GET", "/ABC1", "")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestItAuthenticatesABearerToken(t *testing.T) {
+	var principal authservice.Principal
+	handler := BearerAuth(testAuthenticator{}, RequireScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = authservice.PrincipalFromContext(r.Context())
+	})))
+
+	r := httptest.NewRequest("POST", "http://localhost:8080/api/shorten", nil)
+	r.Header.Set("Authorization", "Bearer valid")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusOK, w.Code)
+	}
+
+	if principal.ID != "user1" {
+		t.Errorf("Expected principal '%s', instead received '%+v'", "user1", principal)
+	}
+}
+
+func TestItRejectsAnInvalidBearerToken(t *testing.T) {
+	handler := BearerAuth(testAuthenticator{}, RequireScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	r.Header.Set("Authorization", "Bearer invalid")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusUnauthorized, w.Code)
+	}
+
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected WWW-Authenticate header")
+	}
+}
+
+func TestItAllowsAnAdminWithoutTheRequiredScope(t *testing.T) {
+	handler := RequireScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest("DELETE", "http://localhost:8080/api/links/ABC1", nil)
+	r = r.WithContext(authservice.WithPrincipal(r.Context(), authservice.Principal{ID: "user1", Scopes: []string{"admin"}}))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusOK, w.Code)
+	}
+}
+
+type testAuthenticator struct{}
+
+func (a testAuthenticator) Authenticate(token string) (authservice.Principal, error) {
+	if token != "valid" {
+		return authservice.Principal{}, errors.New("Invalid token signature")
+	}
+
+	return authservice.Principal{ID: "user1", Scopes: []string{"create"}}, nil
+}
+
+func serveWithAPIKey(repo apikeyfilesystemrepository.FileSystem, method string, path string, secret string) *httptest.ResponseRecorder {
+	handler := APIKeyAuth(repo, RequireScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	r := httptest.NewRequest(method, "http://localhost:8080"+path, nil)
+	if secret != "" {
+		r.Header.Set("X-API-Key", secret)
+	}
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func clearTestKeyData() {
+	dir, _ := os.Getwd()
+	os.Remove(dir + "/keys.json")
+}
+
+func getTestKeyRepository() apikeyfilesystemrepository.FileSystem {
+	clearTestKeyData()
+	dir, _ := os.Getwd()
+
+	return apikeyfilesystemrepository.New(dir)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"http-url-shortener/internal/services/logservice"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// validRequestID restricts which inbound request IDs are propagated, to keep logs and headers clean
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID propagates the inbound X-Request-ID header (or generates a new one),
+// echoing it on the response and attaching it with a request scoped logger to the request context
+func RequestID(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(requestID) {
+			requestID = logservice.NewRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := logservice.WithRequestID(r.Context(), requestID)
+		ctx = logservice.WithLogger(ctx, logger.With("request_id", requestID))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AccessLog logs a record of each request once it has been served
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		logservice.FromContext(r.Context()).Info(
+			"request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", rec.bytes,
+			"remote_ip", remoteIP(r),
+		)
+	})
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"http-url-shortener/internal/services/logservice"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestItPropagatesAnInboundRequestID(t *testing.T) {
+	var received string
+	handler := RequestID(logservice.Discard(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = logservice.RequestID(r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	r.Header.Set("X-Request-ID", "inbound-123")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if received != "inbound-123" {
+		t.Errorf("Expected request ID of '%s' in context, instead received '%s'", "inbound-123", received)
+	}
+
+	if w.Header().Get("X-Request-ID") != "inbound-123" {
+		t.Errorf("Expected request ID header of '%s', instead received '%s'", "inbound-123", w.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestItGeneratesARequestIDWhenInboundIsMissingOrInvalid(t *testing.T) {
+	handler := RequestID(logservice.Discard(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, inbound := range []string{"", "not valid\n"} {
+		r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+		r.Header.Set("X-Request-ID", inbound)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		generated := w.Header().Get("X-Request-ID")
+		if generated == "" || generated == inbound {
+			t.Errorf("Expected a generated request ID, instead received '%s'", generated)
+		}
+	}
+}
+
+func TestItLogsEachRequest(t *testing.T) {
+	buf := bytes.Buffer{}
+
+	handler := RequestID(logservice.New(&buf, "info"), AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})))
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	r.Header.Set("X-Request-ID", "abc123")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	output := buf.String()
+	expected := []string{
+		`"method":"GET"`,
+		`"path":"/ABC1"`,
+		`"status":418`,
+		`"bytes":15`,
+		`"remote_ip":"192.0.2.1"`,
+		`"request_id":"abc123"`,
+		`"duration_ms":`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected access log to contain '%s', instead received '%s'", e, output)
+		}
+	}
+}
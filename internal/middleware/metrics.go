@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"http-url-shortener/internal/services/metricsservice"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	requestsTotal = metricsservice.DefaultRegistry.NewCounter(
+		"shortener_http_requests_total",
+		"Number of HTTP requests, by route, method and status.",
+		"route", "method", "status",
+	)
+	requestDuration = metricsservice.DefaultRegistry.NewHistogram(
+		"shortener_http_request_duration_seconds",
+		"Latency of HTTP requests, by route and status.",
+		metricsservice.DefaultBuckets,
+		"route", "status",
+	)
+)
+
+// Metrics records the count and latency of each request, labelled by the route name returned from route
+func Metrics(route func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		name := route(r)
+		status := strconv.Itoa(rec.status)
+
+		requestsTotal.Inc(name, methodLabel(r.Method), status)
+		requestDuration.Observe(time.Since(start).Seconds(), name, status)
+	})
+}
+
+// methodLabel returns the method of a request as a metric label, grouping unknown methods (which clients may send
+// any number of) as "other" so that they can't create unbounded series
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+
+	return "other"
+}
+
+// responseRecorder captures the status code and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+
+	return n, err
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestItRecordsRequestMetricsByRoute(t *testing.T) {
+	handler := Metrics(
+		func(r *http.Request) string { return "/{code}" },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}),
+	)
+
+	before := requestsTotal.Value("/{code}", "GET", "404")
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	after := requestsTotal.Value("/{code}", "GET", "404")
+	if after != before+1 {
+		t.Errorf("Expected request count of %f, instead received %f", before+1, after)
+	}
+
+	if requestDuration.Count("/{code}", "404") == 0 {
+		t.Errorf("Expected request duration to be observed")
+	}
+}
+
+func TestItRecordsUnknownMethodsAsOther(t *testing.T) {
+	handler := Metrics(
+		func(r *http.Request) string { return "/{code}" },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	before := requestsTotal.Value("/{code}", "other", "200")
+
+	for _, method := range []string{"BREW", "PROPFIND", "get"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "http://localhost:8080/ABC1", nil))
+	}
+
+	if after := requestsTotal.Value("/{code}", "other", "200"); after != before+3 {
+		t.Errorf("Expected request count of %f, instead received %f", before+3, after)
+	}
+
+	if requestsTotal.Value("/{code}", "BREW", "200") != 0 {
+		t.Errorf("Expected no series for unknown method 'BREW'")
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"http-url-shortener/internal/services/authservice"
+	"http-url-shortener/internal/services/logservice"
+	"http-url-shortener/internal/services/metricsservice"
+	"http-url-shortener/internal/services/netservice"
+	"http-url-shortener/internal/services/ratelimitservice"
+	"http-url-shortener/internal/services/responseservice"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var rateLimitedTotal = metricsservice.DefaultRegistry.NewCounter(
+	"shortener_rate_limited_total",
+	"Number of requests rejected for exceeding a rate limit.",
+)
+
+// RateLimit limits each client to the Limiter that limiter returns for its request, which may be nil for no limit
+func RateLimit(limiter func(*http.Request) *ratelimitservice.Limiter, trustProxy bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := limiter(r)
+		if l == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		res := l.Allow(ClientKey(r, trustProxy))
+
+		headers := []string{
+			"X-RateLimit-Limit", strconv.Itoa(res.Limit),
+			"X-RateLimit-Remaining", strconv.Itoa(res.Remaining),
+			"X-RateLimit-Reset", seconds(res.Reset),
+		}
+
+		if !res.Allowed {
+			rateLimitedTotal.Inc()
+			responseservice.NewErrResponse("Rate limit exceeded", http.StatusTooManyRequests).
+				WithHeaders(append(headers, "Retry-After", seconds(res.RetryAfter))...).
+				WithRequestID(logservice.RequestID(r.Context())).
+				WriteNegotiated(w, r)
+			return
+		}
+
+		for i := 0; i < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientKey identifies the client making a request, by its authenticated principal or otherwise its IP address
+func ClientKey(r *http.Request, trustProxy bool) string {
+	if p, ok := authservice.PrincipalFromContext(r.Context()); ok && p.ID != "" {
+		return "principal:" + p.ID
+	}
+
+	return "ip:" + netservice.ClientIP(r, trustProxy)
+}
+
+// seconds formats a duration as a whole number of seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
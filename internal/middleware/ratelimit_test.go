@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"http-url-shortener/internal/services/authservice"
+	"http-url-shortener/internal/services/ratelimitservice"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestItRateLimitsAClient(t *testing.T) {
+	limiter := ratelimitservice.New(1, 2)
+	handler := RateLimit(
+		func(r *http.Request) *ratelimitservice.Limiter { return limiter },
+		false,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest("POST", "http://localhost:8080/api/shorten", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if i == 1 && w.Header().Get("X-RateLimit-Remaining") != "0" {
+			t.Errorf("Expected %s remaining, instead received '%s'", "0", w.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After of '%s', instead received '%s'", "60", w.Header().Get("Retry-After"))
+	}
+
+	if w.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Errorf("Expected limit of '%s', instead received '%s'", "2", w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	if !strings.Contains(w.Body.String(), `"code":"rate_limited"`) {
+		t.Errorf("Expected error code of '%s', instead received '%s'", "rate_limited", w.Body.String())
+	}
+
+	// another client is unaffected
+	r := httptest.NewRequest("POST", "http://localhost:8080/api/shorten", nil)
+	r.RemoteAddr = "192.0.2.2:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestItDoesNotRateLimitWithoutALimiter(t *testing.T) {
+	handler := RateLimit(
+		func(r *http.Request) *ratelimitservice.Limiter { return nil },
+		false,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil))
+
+	if w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("Expected no rate limit headers, instead received '%+v'", w.Header())
+	}
+}
+
+func TestItIdentifiesClientsByPrincipalOrIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7, 192.0.2.1")
+
+	if key := ClientKey(r, false); key != "ip:192.0.2.1" {
+		t.Errorf("Expected key '%s', instead received '%s'", "ip:192.0.2.1", key)
+	}
+
+	if key := ClientKey(r, true); key != "ip:192.0.2.1" {
+		t.Errorf("Expected key '%s', instead received '%s'", "ip:192.0.2.1", key)
+	}
+
+	r = r.WithContext(authservice.WithPrincipal(r.Context(), authservice.Principal{ID: "key1"}))
+	if key := ClientKey(r, true); key != "principal:key1" {
+		t.Errorf("Expected key '%s', instead received '%s'", "principal:key1", key)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"http-url-shortener/internal/services/logservice"
+	"http-url-shortener/internal/services/metricsservice"
+	"net/http"
+	"runtime/debug"
+)
+
+var panicsTotal = metricsservice.DefaultRegistry.NewCounter(
+	"shortener_panics_total",
+	"Number of panics recovered whilst serving requests.",
+)
+
+// Recover converts a panic whilst serving a request into a JSON error response, logging its stack trace
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			// deliberately aborted, let net/http handle it
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			panicsTotal.Inc()
+			logservice.FromContext(r.Context()).Error(
+				"Recovered from panic",
+				"panic", rec,
+				"stack", string(debug.Stack()),
+			)
+
+			writeError(w, r, "Internal server error", http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
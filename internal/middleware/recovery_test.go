@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"http-url-shortener/internal/services/logservice"
+	"http-url-shortener/internal/services/responseservice"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestItRecoversFromAPanic(t *testing.T) {
+	buf := bytes.Buffer{}
+
+	handler := RequestID(logservice.New(&buf, "info"), Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]interface{}
+		_ = m["status"].(string)
+	})))
+
+	before := panicsTotal.Value()
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	r.Header.Set("X-Request-ID", "abc123")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	json := responseservice.ParseJSON(resp)
+
+	if json["status"] != "err" {
+		t.Errorf("Expected JSON status of 'err', instead received '%s'", json["status"])
+	}
+
+	jsonData := json["data"].(map[string]interface{})
+	if jsonData["requestId"] != "abc123" {
+		t.Errorf("Expected requestId of 'abc123', instead received '%s'", jsonData["requestId"])
+	}
+
+	if panicsTotal.Value() != before+1 {
+		t.Errorf("Expected panic count of %f, instead received %f", before+1, panicsTotal.Value())
+	}
+
+	if !strings.Contains(buf.String(), "Recovered from panic") || !strings.Contains(buf.String(), "goroutine") {
+		t.Errorf("Expected panic and stack to be logged, instead received '%s'", buf.String())
+	}
+}
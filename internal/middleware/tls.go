@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// HSTS adds a Strict-Transport-Security header to all responses served over TLS
+func HSTS(maxAge int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && maxAge > 0 {
+			w.Header().Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", maxAge))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RedirectToHTTPS returns a handler that permanently redirects every request to its HTTPS equivalent,
+// except for health and readiness probes which are served by probes so they can be checked over plain HTTP
+func RedirectToHTTPS(httpsAddr string, probes http.Handler) http.Handler {
+	_, httpsPort, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if probes != nil && (r.URL.Path == "/healthz" || r.URL.Path == "/readyz") {
+			probes.ServeHTTP(w, r)
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+
+		// omit the default port from redirect location
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestItAddsHSTSHeaderToTLSRequests(t *testing.T) {
+	handler := HSTS(3600, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest("GET", "https://localhost:8443/ABC1", nil)
+	r.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	expected := "max-age=3600; includeSubDomains"
+	if w.Header().Get("Strict-Transport-Security") != expected {
+		t.Errorf("Expected HSTS header of '%s', instead received '%s'", expected, w.Header().Get("Strict-Transport-Security"))
+	}
+}
+
+func TestItOmitsHSTSHeaderFromPlainRequests(t *testing.T) {
+	handler := HSTS(3600, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Errorf("Expected no HSTS header, instead received '%s'", w.Header().Get("Strict-Transport-Security"))
+	}
+}
+
+func TestItRedirectsToHTTPS(t *testing.T) {
+	tests := map[string]string{
+		":8443": "https://localhost:8443/ABC1?a=b",
+		":443":  "https://localhost/ABC1?a=b",
+	}
+
+	for addr, expected := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080/ABC1?a=b", nil)
+		w := httptest.NewRecorder()
+
+		RedirectToHTTPS(addr, nil).ServeHTTP(w, r)
+
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("Expected status code %d, instead received %d", http.StatusMovedPermanently, w.Code)
+		}
+
+		if w.Header().Get("Location") != expected {
+			t.Errorf("Expected location header '%s', instead received '%s'", expected, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestItServesProbesWithoutRedirectingToHTTPS(t *testing.T) {
+	probes := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		r := httptest.NewRequest("GET", "http://localhost:8080"+path, nil)
+		w := httptest.NewRecorder()
+
+		RedirectToHTTPS(":8443", probes).ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d for '%s', instead received %d", http.StatusOK, path, w.Code)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/version", nil)
+	w := httptest.NewRecorder()
+
+	RedirectToHTTPS(":8443", probes).ServeHTTP(w, r)
+
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected status code %d, instead received %d", http.StatusMovedPermanently, w.Code)
+	}
+}
@@ -0,0 +1,179 @@
+package apikeyfilesystemrepository
+
+import (
+	"encoding/json"
+	"errors"
+	"http-url-shortener/internal/entities/apikey"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// mu serialises read-modify-write cycles on the key store
+var mu sync.Mutex
+
+// FileSystem represents a file system to store API keys on
+type FileSystem struct {
+	basePath string
+}
+
+// record represents an API key as persisted on file system
+type record struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"createdAt"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// New instance of FileSystem type
+func New(p string) FileSystem {
+	return FileSystem{
+		basePath: p,
+	}
+}
+
+// Create a new API key on file system
+func (f FileSystem) Create(k apikey.APIKey) (apikey.APIKey, error) {
+	if k.GetID() == "" || k.GetHash() == "" {
+		// nothing to save
+		return apikey.APIKey{}, errors.New("API key is empty")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := getPathToKeysFile(f)
+	records := loadRecords(path)
+
+	for _, r := range records {
+		if r.ID == k.GetID() || r.Hash == k.GetHash() {
+			// already exists
+			return apikey.APIKey{}, errors.New("API key already exists")
+		}
+	}
+
+	records = append(records, toRecord(k))
+	if saveRecords(path, records) == false {
+		// unable to save
+		return apikey.APIKey{}, errors.New("API key could not be created")
+	}
+
+	return k, nil
+}
+
+// RetrieveByHash retrieves an API key by the hash of its secret
+func (f FileSystem) RetrieveByHash(hash string) (apikey.APIKey, error) {
+	for _, r := range loadRecords(getPathToKeysFile(f)) {
+		if r.Hash == hash {
+			return fromRecord(r), nil
+		}
+	}
+
+	// no matching records
+	return apikey.APIKey{}, errors.New("API key does not exist")
+}
+
+// Revoke marks an API key as revoked
+func (f FileSystem) Revoke(id string) (apikey.APIKey, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := getPathToKeysFile(f)
+	records := loadRecords(path)
+
+	for i, r := range records {
+		if r.ID != id {
+			continue
+		}
+
+		records[i].Revoked = true
+		if saveRecords(path, records) == false {
+			// unable to save
+			return apikey.APIKey{}, errors.New("API key could not be revoked")
+		}
+
+		return fromRecord(records[i]), nil
+	}
+
+	// no matching records
+	return apikey.APIKey{}, errors.New("API key does not exist")
+}
+
+// List all API keys, ordered by creation date
+func (f FileSystem) List() ([]apikey.APIKey, error) {
+	records := loadRecords(getPathToKeysFile(f))
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+
+	keys := []apikey.APIKey{}
+	for _, r := range records {
+		keys = append(keys, fromRecord(r))
+	}
+
+	return keys, nil
+}
+
+func getPathToKeysFile(f FileSystem) string {
+	return f.basePath + "/keys.json"
+}
+
+func toRecord(k apikey.APIKey) record {
+	return record{
+		ID:        k.GetID(),
+		Name:      k.GetName(),
+		Hash:      k.GetHash(),
+		Scopes:    k.GetScopes(),
+		CreatedAt: k.GetCreatedAt(),
+		Revoked:   k.IsRevoked(),
+	}
+}
+
+func fromRecord(r record) apikey.APIKey {
+	k := apikey.New(r.ID, r.Name, r.Hash, r.Scopes, r.CreatedAt)
+	if r.Revoked {
+		k = k.Revoke()
+	}
+
+	return k
+}
+
+func loadRecords(path string) []record {
+	fileContents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []record{}
+	}
+
+	records := []record{}
+
+	err = json.Unmarshal(fileContents, &records)
+	if err != nil {
+		return []record{}
+	}
+
+	return records
+}
+
+func saveRecords(filePath string, records []record) bool {
+	fileContents, err := json.Marshal(records)
+	if err != nil {
+		return false
+	}
+
+	// create file's parent directory if it doesn't exist
+	os.MkdirAll(path.Dir(filePath), 0755)
+
+	// key hashes are sensitive, so restrict to owner
+	err = ioutil.WriteFile(filePath, fileContents, 0600)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,119 @@
+package apikeyfilesystemrepository
+
+import (
+	"http-url-shortener/internal/entities/apikey"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestItFailsToCreateAnAPIKeyIfSuppliedObjectHasNoValues(t *testing.T) {
+	fs := getTestFsRepository()
+
+	_, err := fs.Create(apikey.New("", "marketing", "", nil, time.Now()))
+	if err == nil || err.Error() != "API key is empty" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "API key is empty", err)
+	}
+}
+
+func TestItFailsToCreateAnAPIKeyIfItAlreadyExists(t *testing.T) {
+	clearTestData()
+	fs := getTestFsRepository()
+
+	fs.Create(apikey.New("key1", "marketing", "hash1", []string{apikey.ScopeCreate}, time.Now()))
+
+	_, err := fs.Create(apikey.New("key2", "sales", "hash1", []string{apikey.ScopeCreate}, time.Now()))
+	if err == nil || err.Error() != "API key already exists" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "API key already exists", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyCreatesAndRetrievesAnAPIKeyByHash(t *testing.T) {
+	clearTestData()
+	fs := getTestFsRepository()
+
+	_, err := fs.Create(apikey.New("key1", "marketing", "hash1", []string{apikey.ScopeCreate}, time.Now()))
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	k, err := fs.RetrieveByHash("hash1")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if k.GetID() != "key1" || k.HasScope(apikey.ScopeCreate) != true {
+		t.Errorf("Expected key '%s' with scope '%s', instead received '%+v'", "key1", apikey.ScopeCreate, k)
+	}
+
+	_, err = fs.RetrieveByHash("hash2")
+	if err == nil || err.Error() != "API key does not exist" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "API key does not exist", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyRevokesAnAPIKey(t *testing.T) {
+	clearTestData()
+	fs := getTestFsRepository()
+
+	fs.Create(apikey.New("key1", "marketing", "hash1", []string{apikey.ScopeCreate}, time.Now()))
+
+	revoked, err := fs.Revoke("key1")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if revoked.IsRevoked() != true {
+		t.Errorf("Expected returned key to be revoked")
+	}
+
+	k, _ := fs.RetrieveByHash("hash1")
+	if k.IsRevoked() != true {
+		t.Errorf("Expected stored key to be revoked")
+	}
+
+	_, err = fs.Revoke("key2")
+	if err == nil || err.Error() != "API key does not exist" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "API key does not exist", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItListsAPIKeysInCreationOrder(t *testing.T) {
+	clearTestData()
+	fs := getTestFsRepository()
+
+	now := time.Now()
+	fs.Create(apikey.New("key2", "sales", "hash2", nil, now.Add(time.Minute)))
+	fs.Create(apikey.New("key1", "marketing", "hash1", nil, now))
+
+	keys, err := fs.List()
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if len(keys) != 2 || keys[0].GetID() != "key1" || keys[1].GetID() != "key2" {
+		t.Errorf("Expected keys 'key1' and 'key2' in order, instead received '%+v'", keys)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func clearTestData() {
+	os.Remove(getPathToKeysFile(getTestFsRepository()))
+}
+
+func getTestFsRepository() FileSystem {
+	dir, _ := os.Getwd()
+
+	return New(dir)
+}
@@ -0,0 +1,102 @@
+package instrumentedrepository
+
+import (
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/metricsservice"
+	"time"
+)
+
+var operationDuration = metricsservice.DefaultRegistry.NewHistogram(
+	"shortener_repository_operation_duration_seconds",
+	"Latency of repository operations, by backend and operation.",
+	metricsservice.DefaultBuckets,
+	"backend", "operation",
+)
+
+// Instrumented represents a repository whose operations are timed
+type Instrumented struct {
+	repo repositoryinterface.RepositoryInterface
+}
+
+// New instance of Instrumented type, wrapping the provided repository
+func New(repo repositoryinterface.RepositoryInterface) Instrumented {
+	return Instrumented{
+		repo: repo,
+	}
+}
+
+// Create a new Shortened URL on the wrapped repository
+func (i Instrumented) Create(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedURL, error) {
+	defer i.observe("create", time.Now())
+	return i.repo.Create(u)
+}
+
+// RetrieveByShortCode retrieves a Shortened URL by its short code from the wrapped repository
+func (i Instrumented) RetrieveByShortCode(shortcode string) (shortenedurl.ShortenedURL, error) {
+	defer i.observe("retrieve_by_short_code", time.Now())
+	return i.repo.RetrieveByShortCode(shortcode)
+}
+
+// RetrieveByLongURL retrieves an owner's Shortened URL by its origin (long) URL from the wrapped repository
+func (i Instrumented) RetrieveByLongURL(longURL string, owner string) (shortenedurl.ShortenedURL, error) {
+	defer i.observe("retrieve_by_long_url", time.Now())
+	return i.repo.RetrieveByLongURL(longURL, owner)
+}
+
+// Update an existing Shortened URL on the wrapped repository
+func (i Instrumented) Update(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedURL, error) {
+	defer i.observe("update", time.Now())
+	return i.repo.Update(u)
+}
+
+// Delete a Shortened URL from the wrapped repository
+func (i Instrumented) Delete(shortcode string) error {
+	defer i.observe("delete", time.Now())
+	return i.repo.Delete(shortcode)
+}
+
+// Rename changes the short code of a Shortened URL on the wrapped repository
+func (i Instrumented) Rename(shortcode string, newShortcode string) (shortenedurl.ShortenedURL, error) {
+	defer i.observe("rename", time.Now())
+	return i.repo.Rename(shortcode, newShortcode)
+}
+
+// RecordClick increments the number of times a Shortened URL, and optionally one of its variants, on the wrapped repository has been followed
+func (i Instrumented) RecordClick(shortcode string, variant string) (shortenedurl.ShortenedURL, error) {
+	defer i.observe("record_click", time.Now())
+	return i.repo.RecordClick(shortcode, variant)
+}
+
+// SetThreat changes the threat that a Shortened URL on the wrapped repository has been flagged as
+func (i Instrumented) SetThreat(shortcode string, threat string) (shortenedurl.ShortenedURL, error) {
+	defer i.observe("set_threat", time.Now())
+	return i.repo.SetThreat(shortcode, threat)
+}
+
+// List Shortened URLs on the wrapped repository
+func (i Instrumented) List(owner string) ([]shortenedurl.ShortenedURL, error) {
+	defer i.observe("list", time.Now())
+	return i.repo.List(owner)
+}
+
+// Count returns the number of Shortened URLs on the wrapped repository
+func (i Instrumented) Count(owner string) (int, error) {
+	defer i.observe("count", time.Now())
+	return i.repo.Count(owner)
+}
+
+// Ping checks that the wrapped repository is reachable
+func (i Instrumented) Ping() error {
+	defer i.observe("ping", time.Now())
+	return i.repo.Ping()
+}
+
+// Backend returns the name of the wrapped repository's storage backend
+func (i Instrumented) Backend() string {
+	return i.repo.Backend()
+}
+
+func (i Instrumented) observe(operation string, start time.Time) {
+	operationDuration.Observe(time.Since(start).Seconds(), i.repo.Backend(), operation)
+}
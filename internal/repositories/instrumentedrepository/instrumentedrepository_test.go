@@ -0,0 +1,35 @@
+package instrumentedrepository
+
+import (
+	"http-url-shortener/internal/repositories/shortenedurlfilesystemrepository"
+	"os"
+	"testing"
+)
+
+func TestItObservesRepositoryOperationLatency(t *testing.T) {
+	dir, _ := os.Getwd()
+	repo := New(shortenedurlfilesystemrepository.New(dir))
+
+	before := operationDuration.Count("filesystem", "retrieve_by_short_code")
+
+	repo.RetrieveByShortCode("ABC1")
+
+	after := operationDuration.Count("filesystem", "retrieve_by_short_code")
+	if after != before+1 {
+		t.Errorf("Expected %d observations, instead received %d", before+1, after)
+	}
+}
+
+func TestItDelegatesToTheWrappedRepository(t *testing.T) {
+	dir, _ := os.Getwd()
+	repo := New(shortenedurlfilesystemrepository.New(dir))
+
+	if repo.Backend() != "filesystem" {
+		t.Errorf("Expected backend of '%s', instead received '%s'", "filesystem", repo.Backend())
+	}
+
+	_, err := repo.RetrieveByShortCode("ABC1")
+	if err == nil || err.Error() != "Shortened URL does not exist" {
+		t.Errorf("Expected error '%s', instead received '%v'", "Shortened URL does not exist", err)
+	}
+}
@@ -1,10 +1,43 @@
 package repositoryinterface
 
-import "http-url-shortener/internal/entities/shortenedurl"
+import (
+	"errors"
+	"http-url-shortener/internal/entities/apikey"
+	"http-url-shortener/internal/entities/shortenedurl"
+)
+
+// ErrShortenedURLExists is returned when creating or renaming a Shortened URL whose short code (or long URL, for its owner) is already taken
+var ErrShortenedURLExists = errors.New("Shortened URL already exists")
+
+// ErrShortenedURLNotFound is returned when a Shortened URL to retrieve or change does not exist
+var ErrShortenedURLNotFound = errors.New("Shortened URL does not exist")
+
+// ErrClicksExhausted is returned when recording a click on a Shortened URL that has already been followed as many times as it may be
+var ErrClicksExhausted = errors.New("Shortened URL has no clicks remaining")
 
 // RepositoryInterface defines interface for a Shortened URL repository
 type RepositoryInterface interface {
 	Create(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedURL, error)
 	RetrieveByShortCode(shortcode string) (shortenedurl.ShortenedURL, error)
-	RetrieveByLongURL(longURL string) (shortenedurl.ShortenedURL, error)
+	RetrieveByLongURL(longURL string, owner string) (shortenedurl.ShortenedURL, error)
+	Update(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedURL, error)
+	Delete(shortcode string) error
+	Rename(shortcode string, newShortcode string) (shortenedurl.ShortenedURL, error)
+	// RecordClick atomically increments the clicks of a Shortened URL, and optionally of one of its variants,
+	// unless it has reached its maximum clicks in which case ErrClicksExhausted is returned
+	RecordClick(shortcode string, variant string) (shortenedurl.ShortenedURL, error)
+	// SetThreat atomically changes only the threat that a Shortened URL has been flagged as, leaving its other properties as stored
+	SetThreat(shortcode string, threat string) (shortenedurl.ShortenedURL, error)
+	List(owner string) ([]shortenedurl.ShortenedURL, error)
+	Count(owner string) (int, error)
+	Ping() error
+	Backend() string
+}
+
+// APIKeyRepositoryInterface defines interface for an API key repository
+type APIKeyRepositoryInterface interface {
+	Create(k apikey.APIKey) (apikey.APIKey, error)
+	RetrieveByHash(hash string) (apikey.APIKey, error)
+	Revoke(id string) (apikey.APIKey, error)
+	List() ([]apikey.APIKey, error)
 }
@@ -3,25 +3,75 @@ package shortenedurlfilesystemrepository
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path"
+	"sort"
+	"sync"
+	"time"
 )
 
+// mu serialises read-modify-write cycles on the manifest
+var mu sync.Mutex
+
 // FileSystem represents a file system to perform operations on
 type FileSystem struct {
 	basePath string
+	logger   *slog.Logger
+}
+
+// record represents a Shortened URL as persisted in the manifest, keyed by its short code
+type record struct {
+	Long         string          `json:"long"`
+	Owner        string          `json:"owner,omitempty"`
+	Threat       string          `json:"threat,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	CreatedAt    int64           `json:"createdAt,omitempty"`
+	Clicks       int             `json:"clicks,omitempty"`
+	Interstitial bool            `json:"interstitial,omitempty"`
+	Passthrough  bool            `json:"passthrough,omitempty"`
+	Campaign     string          `json:"campaign,omitempty"`
+	Rules        []ruleRecord    `json:"rules,omitempty"`
+	Variants     []variantRecord `json:"variants,omitempty"`
+	PasswordHash string          `json:"passwordHash,omitempty"`
+	MaxClicks    int             `json:"maxClicks,omitempty"`
+	ActiveFrom   int64           `json:"activeFrom,omitempty"`
+	ActiveUntil  int64           `json:"activeUntil,omitempty"`
+}
+
+type ruleRecord struct {
+	URL       string   `json:"url"`
+	Platforms []string `json:"platforms,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	From      string   `json:"from,omitempty"`
+	Until     string   `json:"until,omitempty"`
+}
+
+type variantRecord struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int    `json:"clicks,omitempty"`
 }
 
 // New instance of FileSystem type
 func New(p string) FileSystem {
 	return FileSystem{
 		basePath: p,
+		logger:   slog.Default(),
 	}
 }
 
+// WithLogger returns a copy of the FileSystem that logs to the provided logger
+func (f FileSystem) WithLogger(logger *slog.Logger) FileSystem {
+	f.logger = logger
+	return f
+}
+
 // Create a new Shortened URL on file system
 func (f FileSystem) Create(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedURL, error) {
 	if u.GetLong() == "" || u.GetShort() == "" {
@@ -29,15 +79,18 @@ func (f FileSystem) Create(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedU
 		return shortenedurl.ShortenedURL{}, errors.New("Shortened URL is empty")
 	}
 
+	mu.Lock()
+	defer mu.Unlock()
+
 	path := getPathToDbFile(f)
-	m := loadManifest(path)
+	m := loadManifest(path, f.logger)
 
-	if m[u.GetLong()] != "" {
+	if _, ok := m[u.GetShort()]; ok || (u.IsReusable() && findByLongURL(m, u.GetLong(), u.GetOwner()) != "") {
 		// already exists
-		return shortenedurl.ShortenedURL{}, errors.New("Shortened URL already exists")
+		return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLExists
 	}
 
-	m[u.GetLong()] = u.GetShort()
+	m[u.GetShort()] = toRecord(u)
 	if saveManifest(path, m) == false {
 		// unable to save
 		return shortenedurl.ShortenedURL{}, errors.New("Shortened URL could not be created")
@@ -48,54 +101,352 @@ func (f FileSystem) Create(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedU
 
 // RetrieveByShortCode retrieves a Shortened URL by its short code
 func (f FileSystem) RetrieveByShortCode(shortcode string) (shortenedurl.ShortenedURL, error) {
-	m := loadManifest(getPathToDbFile(f))
+	m := loadManifest(getPathToDbFile(f), f.logger)
 
 	// try to retrieve by URL's short code
-	for l, s := range m {
-		if s == shortcode {
-			return shortenedurl.New(l, s), nil
-		}
+	if r, ok := m[shortcode]; ok {
+		return fromRecord(shortcode, r), nil
 	}
 
 	// no matching manifest entries
-	return shortenedurl.ShortenedURL{}, errors.New("Shortened URL does not exist")
+	return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLNotFound
 }
 
-// RetrieveByLongURL retrieves a Shortened URL by its origin (long) URL
-func (f FileSystem) RetrieveByLongURL(longURL string) (shortenedurl.ShortenedURL, error) {
-	m := loadManifest(getPathToDbFile(f))
+// RetrieveByLongURL retrieves a Shortened URL by its origin (long) URL, within the provided owner's namespace
+func (f FileSystem) RetrieveByLongURL(longURL string, owner string) (shortenedurl.ShortenedURL, error) {
+	m := loadManifest(getPathToDbFile(f), f.logger)
 
 	// try to retrieve by origin (long) URL
-	if m[longURL] != "" {
-		return shortenedurl.New(longURL, m[longURL]), nil
+	if s := findByLongURL(m, longURL, owner); s != "" {
+		return fromRecord(s, m[s]), nil
 	}
 
 	// no matching manifest entries
-	return shortenedurl.ShortenedURL{}, errors.New("Shortened URL does not exist")
+	return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLNotFound
+}
+
+// Update an existing Shortened URL on file system, identified by its short code. Its clicks are kept as stored,
+// rather than taken from the provided Shortened URL, which may have been retrieved before further clicks were recorded
+func (f FileSystem) Update(u shortenedurl.ShortenedURL) (shortenedurl.ShortenedURL, error) {
+	if u.GetLong() == "" || u.GetShort() == "" {
+		// nothing to save
+		return shortenedurl.ShortenedURL{}, errors.New("Shortened URL is empty")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := getPathToDbFile(f)
+	m := loadManifest(path, f.logger)
+
+	stored, ok := m[u.GetShort()]
+	if !ok {
+		return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLNotFound
+	}
+
+	r := withClicksOf(toRecord(u), stored)
+
+	m[u.GetShort()] = r
+	if saveManifest(path, m) == false {
+		// unable to save
+		return shortenedurl.ShortenedURL{}, errors.New("Shortened URL could not be updated")
+	}
+
+	return fromRecord(u.GetShort(), r), nil
+}
+
+// Delete a Shortened URL from file system by its short code
+func (f FileSystem) Delete(shortcode string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := getPathToDbFile(f)
+	m := loadManifest(path, f.logger)
+
+	if _, ok := m[shortcode]; !ok {
+		return repositoryinterface.ErrShortenedURLNotFound
+	}
+
+	delete(m, shortcode)
+	if saveManifest(path, m) == false {
+		// unable to save
+		return errors.New("Shortened URL could not be deleted")
+	}
+
+	return nil
+}
+
+// Rename changes the short code of a Shortened URL on file system, provided the new short code is not already taken
+func (f FileSystem) Rename(shortcode string, newShortcode string) (shortenedurl.ShortenedURL, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := getPathToDbFile(f)
+	m := loadManifest(path, f.logger)
+
+	r, ok := m[shortcode]
+	if !ok {
+		return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLNotFound
+	}
+
+	if _, ok := m[newShortcode]; ok {
+		return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLExists
+	}
+
+	delete(m, shortcode)
+	m[newShortcode] = r
+	if saveManifest(path, m) == false {
+		// unable to save
+		return shortenedurl.ShortenedURL{}, errors.New("Shortened URL could not be renamed")
+	}
+
+	return fromRecord(newShortcode, r), nil
+}
+
+// RecordClick increments the number of times a Shortened URL, and optionally the named one of its variants, has been followed,
+// returning the updated Shortened URL
+func (f FileSystem) RecordClick(shortcode string, variant string) (shortenedurl.ShortenedURL, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := getPathToDbFile(f)
+	m := loadManifest(path, f.logger)
+
+	r, ok := m[shortcode]
+	if !ok {
+		return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLNotFound
+	}
+
+	// check and increment under the same lock, so that concurrent clicks can't exceed the maximum
+	if r.MaxClicks > 0 && r.Clicks >= r.MaxClicks {
+		return shortenedurl.ShortenedURL{}, repositoryinterface.ErrClicksExhausted
+	}
+
+	r.Clicks++
+	for i := range r.Variants {
+		if r.Variants[i].Name == variant {
+			r.Variants[i].Clicks++
+		}
+	}
+
+	m[shortcode] = r
+	if saveManifest(path, m) == false {
+		// unable to save
+		return shortenedurl.ShortenedURL{}, errors.New("Click could not be recorded")
+	}
+
+	return fromRecord(shortcode, r), nil
+}
+
+// SetThreat changes the threat that a Shortened URL has been flagged as, returning the updated Shortened URL
+func (f FileSystem) SetThreat(shortcode string, threat string) (shortenedurl.ShortenedURL, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := getPathToDbFile(f)
+	m := loadManifest(path, f.logger)
+
+	r, ok := m[shortcode]
+	if !ok {
+		return shortenedurl.ShortenedURL{}, repositoryinterface.ErrShortenedURLNotFound
+	}
+
+	r.Threat = threat
+
+	m[shortcode] = r
+	if saveManifest(path, m) == false {
+		// unable to save
+		return shortenedurl.ShortenedURL{}, errors.New("Threat could not be set")
+	}
+
+	return fromRecord(shortcode, r), nil
+}
+
+// List Shortened URLs on file system ordered by short code, optionally restricted to the provided owner
+func (f FileSystem) List(owner string) ([]shortenedurl.ShortenedURL, error) {
+	m := loadManifest(getPathToDbFile(f), f.logger)
+
+	codes := []string{}
+	for s, r := range m {
+		if owner == "" || r.Owner == owner {
+			codes = append(codes, s)
+		}
+	}
+	sort.Strings(codes)
+
+	urls := []shortenedurl.ShortenedURL{}
+	for _, s := range codes {
+		urls = append(urls, fromRecord(s, m[s]))
+	}
+
+	return urls, nil
+}
+
+// Count returns the number of Shortened URLs on file system, optionally restricted to the provided owner
+func (f FileSystem) Count(owner string) (int, error) {
+	urls, err := f.List(owner)
+	return len(urls), err
+}
+
+// Ping checks that the file system's base path exists and is writable
+func (f FileSystem) Ping() error {
+	if err := os.MkdirAll(f.basePath, 0755); err != nil {
+		return err
+	}
+
+	probe, err := ioutil.TempFile(f.basePath, ".ping")
+	if err != nil {
+		return err
+	}
+
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// Backend returns the name of the file system storage backend
+func (f FileSystem) Backend() string {
+	return "filesystem"
 }
 
 func getPathToDbFile(f FileSystem) string {
 	return f.basePath + "/db.txt"
 }
 
-func loadManifest(path string) map[string]string {
+// findByLongURL returns the short code of the owner's reusable Shortened URL for the long URL, if any
+func findByLongURL(m map[string]record, longURL string, owner string) string {
+	for s, r := range m {
+		if r.Long == longURL && r.Owner == owner && fromRecord(s, r).IsReusable() {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// withClicksOf returns a copy of the record with the clicks of the stored record, and of its variants of the same name
+func withClicksOf(r record, stored record) record {
+	r.Clicks = stored.Clicks
+
+	clicks := map[string]int{}
+	for _, variant := range stored.Variants {
+		clicks[variant.Name] = variant.Clicks
+	}
+
+	for i := range r.Variants {
+		r.Variants[i].Clicks = clicks[r.Variants[i].Name]
+	}
+
+	return r
+}
+
+func toRecord(u shortenedurl.ShortenedURL) record {
+	r := record{
+		Long:         u.GetLong(),
+		Owner:        u.GetOwner(),
+		Threat:       u.GetThreat(),
+		Title:        u.GetTitle(),
+		Clicks:       u.GetClicks(),
+		Interstitial: u.HasInterstitial(),
+		Passthrough:  u.HasPassthrough(),
+		Campaign:     u.GetCampaign(),
+		PasswordHash: u.GetPasswordHash(),
+		MaxClicks:    u.GetMaxClicks(),
+	}
+
+	if !u.GetCreatedAt().IsZero() {
+		r.CreatedAt = u.GetCreatedAt().Unix()
+	}
+
+	if !u.GetActiveFrom().IsZero() {
+		r.ActiveFrom = u.GetActiveFrom().Unix()
+	}
+
+	if !u.GetActiveUntil().IsZero() {
+		r.ActiveUntil = u.GetActiveUntil().Unix()
+	}
+
+	for _, rule := range u.GetRules() {
+		r.Rules = append(r.Rules, ruleRecord(rule))
+	}
+
+	for _, variant := range u.GetVariants() {
+		r.Variants = append(r.Variants, variantRecord(variant))
+	}
+
+	return r
+}
+
+func fromRecord(shortcode string, r record) shortenedurl.ShortenedURL {
+	u := shortenedurl.New(r.Long, shortcode).
+		WithOwner(r.Owner).
+		WithThreat(r.Threat).
+		WithTitle(r.Title).
+		WithClicks(r.Clicks).
+		WithInterstitial(r.Interstitial).
+		WithPassthrough(r.Passthrough).
+		WithCampaign(r.Campaign).
+		WithPasswordHash(r.PasswordHash).
+		WithMaxClicks(r.MaxClicks)
+
+	if r.CreatedAt != 0 {
+		u = u.WithCreatedAt(time.Unix(r.CreatedAt, 0).UTC())
+	}
+
+	if r.ActiveFrom != 0 || r.ActiveUntil != 0 {
+		u = u.WithActiveWindow(unixOrZero(r.ActiveFrom), unixOrZero(r.ActiveUntil))
+	}
+
+	if len(r.Rules) > 0 {
+		rules := []shortenedurl.Rule{}
+		for _, rule := range r.Rules {
+			rules = append(rules, shortenedurl.Rule(rule))
+		}
+
+		u = u.WithRules(rules)
+	}
+
+	if len(r.Variants) > 0 {
+		variants := []shortenedurl.Variant{}
+		for _, variant := range r.Variants {
+			variants = append(variants, shortenedurl.Variant(variant))
+		}
+
+		u = u.WithVariants(variants)
+	}
+
+	return u
+}
+
+func loadManifest(path string, logger *slog.Logger) map[string]record {
 	fileContents, err := ioutil.ReadFile(path)
 	if err != nil {
-		return map[string]string{}
+		return map[string]record{}
 	}
 
-	m := map[string]string{}
+	m := map[string]record{}
 
 	err = json.Unmarshal(fileContents, &m)
-	if err != nil {
-		fmt.Println(err)
-		return map[string]string{}
+	if err == nil {
+		return m
+	}
+
+	// fall back to legacy manifest, which maps long URLs to short codes
+	legacy := map[string]string{}
+	if json.Unmarshal(fileContents, &legacy) != nil {
+		logger.Error("Failed to parse manifest", "path", path, "error", err)
+		return map[string]record{}
+	}
+
+	// discard any entries partially decoded before the failure
+	m = map[string]record{}
+	for l, s := range legacy {
+		m[s] = record{Long: l}
 	}
 
 	return m
 }
 
-func saveManifest(filePath string, data map[string]string) bool {
+func saveManifest(filePath string, data map[string]record) bool {
 	fileContents, err := json.Marshal(data)
 	if err != nil {
 		return false
@@ -104,10 +455,33 @@ func saveManifest(filePath string, data map[string]string) bool {
 	// create file's parent directory if it doesn't exist
 	os.MkdirAll(path.Dir(filePath), 0755)
 
-	err = ioutil.WriteFile(filePath, fileContents, 0644)
+	// write to a temporary file that replaces the manifest once complete, so that readers never see a partial write
+	tmp, err := ioutil.TempFile(path.Dir(filePath), "."+path.Base(filePath))
 	if err != nil {
 		return false
 	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(fileContents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false
+	}
+
+	if os.Chmod(tmp.Name(), 0644) != nil || os.Rename(tmp.Name(), filePath) != nil {
+		return false
+	}
 
 	return true
 }
+
+// unixOrZero converts persisted Unix seconds to a time, treating 0 as unset
+func unixOrZero(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0).UTC()
+}
@@ -1,10 +1,15 @@
 package shortenedurlfilesystemrepository
 
 import (
+	"bytes"
 	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -80,7 +85,7 @@ func TestItSuccessfullyCreatesAShortenedURLIfNoDataAlreadyExists(t *testing.T) {
 		t.Errorf("Not expecting error, instead received '%s'", err.Error())
 	}
 
-	if result != shortenedURL {
+	if !reflect.DeepEqual(result, shortenedURL) {
 		t.Errorf(
 			"Expected identical ShortenedURL objects, instead received '%+v' and '%+v'",
 			result,
@@ -103,7 +108,7 @@ func TestItSuccessfullyCreatesAShortenedURLIfDifferentDataAlreadyExists(t *testi
 		t.Errorf("Not expecting error, instead received '%s'", err.Error())
 	}
 
-	if result != shortenedURL {
+	if !reflect.DeepEqual(result, shortenedURL) {
 		t.Errorf(
 			"Expected identical ShortenedURL objects, instead received '%+v' and '%+v'",
 			result,
@@ -173,7 +178,7 @@ func TestItFailsToRetrieveBySLongURLThatDoesNotExist(t *testing.T) {
 
 	fs := getTestFsRepository()
 
-	_, err := fs.RetrieveByLongURL("http://wikipedia.org")
+	_, err := fs.RetrieveByLongURL("http://wikipedia.org", "")
 
 	if err.Error() != "Shortened URL does not exist" {
 		t.Errorf(
@@ -187,13 +192,32 @@ func TestItFailsToRetrieveBySLongURLThatDoesNotExist(t *testing.T) {
 	clearTestData()
 }
 
+func TestItSuccessfullyRetrievesTheActiveWindow(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "activeFrom": 1767225600}}`)
+
+	fs := getTestFsRepository()
+
+	u, err := fs.RetrieveByShortCode("ABC1")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if u.GetActiveFrom().Unix() != 1767225600 || !u.GetActiveUntil().IsZero() {
+		t.Errorf("Expected link active from %d without end, instead received '%+v'", 1767225600, u)
+	}
+
+	// clean up
+	clearTestData()
+}
+
 func TestItSuccessfullyRetrievesByLongURL(t *testing.T) {
 	// set expected data
 	setTestData(`{"http://bbc.co.uk": "ABC1"}`)
 
 	fs := getTestFsRepository()
 
-	shortenedURL, err := fs.RetrieveByLongURL("http://bbc.co.uk")
+	shortenedURL, err := fs.RetrieveByLongURL("http://bbc.co.uk", "")
 
 	if err != nil {
 		t.Errorf("Not expecting error, instead received '%s'", err.Error())
@@ -219,6 +243,352 @@ func TestItSuccessfullyRetrievesByLongURL(t *testing.T) {
 	clearTestData()
 }
 
+func TestItScopesLongURLsToTheirOwner(t *testing.T) {
+	// clean up
+	clearTestData()
+
+	fs := getTestFsRepository()
+
+	_, err := fs.Create(shortenedurl.New("http://bbc.co.uk", "ABC1").WithOwner("key1"))
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	// a different owner may shorten the same long URL
+	_, err = fs.Create(shortenedurl.New("http://bbc.co.uk", "DEF2").WithOwner("key2"))
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	shortenedURL, err := fs.RetrieveByLongURL("http://bbc.co.uk", "key2")
+	if err != nil || shortenedURL.GetShort() != "DEF2" || shortenedURL.GetOwner() != "key2" {
+		t.Errorf("Expected shortcode '%s' owned by '%s', instead received '%+v' (%v)", "DEF2", "key2", shortenedURL, err)
+	}
+
+	_, err = fs.RetrieveByLongURL("http://bbc.co.uk", "key3")
+	if err == nil {
+		t.Errorf("Expected error when owner has not shortened long URL, instead received nil")
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItFailsToCreateAShortenedURLIfShortCodeAlreadyExists(t *testing.T) {
+	// set expected data
+	setTestData(`{"http://bbc.co.uk": "ABC1"}`)
+
+	fs := getTestFsRepository()
+
+	_, err := fs.Create(shortenedurl.New("http://wikipedia.org", "ABC1"))
+	if err == nil || err.Error() != "Shortened URL already exists" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Shortened URL already exists", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyUpdatesAShortenedURL(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}}`)
+
+	fs := getTestFsRepository()
+
+	_, err := fs.Update(shortenedurl.New("http://wikipedia.org", "ABC1").WithOwner("key1"))
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	shortenedURL, _ := fs.RetrieveByShortCode("ABC1")
+	if shortenedURL.GetLong() != "http://wikipedia.org" || shortenedURL.GetOwner() != "key1" {
+		t.Errorf("Expected updated long URL '%s', instead received '%+v'", "http://wikipedia.org", shortenedURL)
+	}
+
+	_, err = fs.Update(shortenedurl.New("http://wikipedia.org", "DEF2"))
+	if err == nil || err.Error() != "Shortened URL does not exist" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Shortened URL does not exist", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItKeepsClicksRecordedSinceAShortenedURLWasRetrievedWhenUpdating(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "maxClicks": 1, "variants": [{"name": "a", "url": "http://bbc.co.uk/a", "weight": 1}]}}`)
+
+	fs := getTestFsRepository()
+
+	retrieved, _ := fs.RetrieveByShortCode("ABC1")
+
+	if _, err := fs.RecordClick("ABC1", "a"); err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	u, err := fs.Update(retrieved.WithTitle("BBC"))
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if u.GetTitle() != "BBC" || u.GetClicks() != 1 || u.GetVariants()[0].Clicks != 1 {
+		t.Errorf("Expected the updated link to keep its click, instead received '%+v'", u)
+	}
+
+	if u, _ := fs.RetrieveByShortCode("ABC1"); u.GetClicks() != 1 || u.IsExhausted() != true {
+		t.Errorf("Expected the saved link to keep its click, instead received '%+v'", u)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyDeletesAShortenedURL(t *testing.T) {
+	// set expected data
+	setTestData(`{"http://bbc.co.uk": "ABC1"}`)
+
+	fs := getTestFsRepository()
+
+	if err := fs.Delete("ABC1"); err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if _, err := fs.RetrieveByShortCode("ABC1"); err == nil {
+		t.Errorf("Expected deleted short code not to exist")
+	}
+
+	if err := fs.Delete("ABC1"); err == nil || err.Error() != "Shortened URL does not exist" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Shortened URL does not exist", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyRenamesAShortenedURL(t *testing.T) {
+	// set expected data
+	setTestData(`{"abc1": {"long": "http://bbc.co.uk", "owner": "key1"}, "DEF2": {"long": "http://wikipedia.org"}}`)
+
+	fs := getTestFsRepository()
+
+	u, err := fs.Rename("abc1", "ABC1")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if u.GetShort() != "ABC1" || u.GetOwner() != "key1" {
+		t.Errorf("Expected renamed Shortened URL to retain its owner, instead received '%+v'", u)
+	}
+
+	if _, err := fs.RetrieveByShortCode("abc1"); err == nil {
+		t.Errorf("Expected previous short code not to exist")
+	}
+
+	if _, err := fs.Rename("ABC1", "DEF2"); err == nil || err.Error() != "Shortened URL already exists" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Shortened URL already exists", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyRecordsAClick(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "title": "BBC", "createdAt": 1577934245, "clicks": 2}}`)
+
+	fs := getTestFsRepository()
+
+	u, err := fs.RecordClick("ABC1", "")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if u.GetClicks() != 3 || u.GetTitle() != "BBC" || u.GetCreatedAt().Unix() != 1577934245 {
+		t.Errorf("Expected 3 clicks on the existing link, instead received '%+v'", u)
+	}
+
+	if u, _ := fs.RetrieveByShortCode("ABC1"); u.GetClicks() != 3 {
+		t.Errorf("Expected %d clicks to be saved, instead received %d", 3, u.GetClicks())
+	}
+
+	if _, err := fs.RecordClick("DEF2", ""); err == nil || err.Error() != "Shortened URL does not exist" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Shortened URL does not exist", err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyRecordsAClickOnAVariant(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "variants": [{"name": "a", "url": "http://bbc.co.uk/a", "weight": 70}, {"name": "b", "url": "http://bbc.co.uk/b", "weight": 30, "clicks": 4}]}}`)
+
+	fs := getTestFsRepository()
+
+	u, err := fs.RecordClick("ABC1", "b")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	variants := u.GetVariants()
+	if u.GetClicks() != 1 || variants[0].Clicks != 0 || variants[1].Clicks != 5 {
+		t.Errorf("Expected a click on variant 'b', instead received '%+v'", u)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItFailsToRecordAClickOnceMaxClicksAreExhausted(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "clicks": 1, "maxClicks": 2}}`)
+
+	fs := getTestFsRepository()
+
+	u, err := fs.RecordClick("ABC1", "")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if u.GetClicks() != 2 || u.IsExhausted() != true {
+		t.Errorf("Expected the link to be exhausted after 2 clicks, instead received '%+v'", u)
+	}
+
+	if _, err := fs.RecordClick("ABC1", ""); err != repositoryinterface.ErrClicksExhausted {
+		t.Errorf("Expected error of '%v', instead received '%v'", repositoryinterface.ErrClicksExhausted, err)
+	}
+
+	if u, _ := fs.RetrieveByShortCode("ABC1"); u.GetClicks() != 2 {
+		t.Errorf("Expected %d clicks to be saved, instead received %d", 2, u.GetClicks())
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItRecordsNoMoreThanMaxClicksConcurrently(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "maxClicks": 5}}`)
+
+	fs := getTestFsRepository()
+
+	var wg sync.WaitGroup
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := fs.RecordClick("ABC1", "")
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	recorded := 0
+	for err := range results {
+		if err == nil {
+			recorded++
+		} else if err != repositoryinterface.ErrClicksExhausted {
+			t.Errorf("Expected error of '%v', instead received '%v'", repositoryinterface.ErrClicksExhausted, err)
+		}
+	}
+
+	if recorded != 5 {
+		t.Errorf("Expected %d clicks to be recorded, instead received %d", 5, recorded)
+	}
+
+	if u, _ := fs.RetrieveByShortCode("ABC1"); u.GetClicks() != 5 {
+		t.Errorf("Expected %d clicks to be saved, instead received %d", 5, u.GetClicks())
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullySetsTheThreatOfAShortenedURL(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "title": "BBC", "clicks": 3}}`)
+
+	fs := getTestFsRepository()
+
+	u, err := fs.SetThreat("ABC1", "malware")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if u.GetThreat() != "malware" || u.GetTitle() != "BBC" || u.GetClicks() != 3 {
+		t.Errorf("Expected only the threat to be set, instead received '%+v'", u)
+	}
+
+	if _, err := fs.SetThreat("DEF2", "malware"); err != repositoryinterface.ErrShortenedURLNotFound {
+		t.Errorf("Expected error of '%v', instead received '%v'", repositoryinterface.ErrShortenedURLNotFound, err)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItListsShortenedURLsByOwner(t *testing.T) {
+	// set expected data
+	setTestData(`{"DEF2": {"long": "http://wikipedia.org", "owner": "key1"}, "ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}, "GHI3": {"long": "http://bbc.co.uk", "owner": "key2"}}`)
+
+	fs := getTestFsRepository()
+
+	all, _ := fs.List("")
+	if len(all) != 3 {
+		t.Errorf("Expected %d shortened URLs, instead received %d", 3, len(all))
+	}
+
+	owned, _ := fs.List("key1")
+	if len(owned) != 2 || owned[0].GetShort() != "ABC1" || owned[1].GetShort() != "DEF2" {
+		t.Errorf("Expected short codes 'ABC1' and 'DEF2' in order, instead received '%+v'", owned)
+	}
+
+	count, _ := fs.Count("key2")
+	if count != 1 {
+		t.Errorf("Expected count of %d, instead received %d", 1, count)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItCountsShortenedURLs(t *testing.T) {
+	// set expected data
+	setTestData(`{"http://bbc.co.uk": "ABC1", "http://wikipedia.org": "DEF2"}`)
+
+	fs := getTestFsRepository()
+
+	count, err := fs.Count("")
+	if err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if count != 2 {
+		t.Errorf("Expected count of %d, instead received %d", 2, count)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyPingsAWritableBasePath(t *testing.T) {
+	fs := getTestFsRepository()
+
+	if err := fs.Ping(); err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+}
+
+func TestItFailsToPingAnUnwritableBasePath(t *testing.T) {
+	fs := New("/dev/null/data")
+
+	if err := fs.Ping(); err == nil {
+		t.Errorf("Expected error when base path is not writable, instead received nil")
+	}
+}
+
 func TestItGetsPathToDBFile(t *testing.T) {
 	fs := getTestFsRepository()
 	path := getPathToDbFile(fs)
@@ -231,21 +601,62 @@ func TestItGetsPathToDBFile(t *testing.T) {
 }
 
 func TestItSuccessfullyLoadsManifest(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk", "owner": "key1"}, "DEF2": {"long": "http://wikipedia.org"}}`)
+
+	m := loadManifest(getTestDataPath(), slog.Default())
+
+	if len(m) != 2 {
+		t.Errorf("Expected manifest length of %d, instead received %d", 2, len(m))
+	}
+
+	if m["ABC1"].Long != "http://bbc.co.uk" || m["ABC1"].Owner != "key1" {
+		t.Errorf("Expected manifest value of '%s' owned by '%s', instead received '%+v'", "http://bbc.co.uk", "key1", m["ABC1"])
+	}
+
+	if m["DEF2"].Long != "http://wikipedia.org" || m["DEF2"].Owner != "" {
+		t.Errorf("Expected unowned manifest value of '%s', instead received '%+v'", "http://wikipedia.org", m["DEF2"])
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItSuccessfullyLoadsLegacyManifest(t *testing.T) {
 	// set expected data
 	setTestData(`{"hello": "world", "bonjour": "monde"}`)
 
-	m := loadManifest(getTestDataPath())
+	m := loadManifest(getTestDataPath(), slog.Default())
 
 	if len(m) != 2 {
 		t.Errorf("Expected manifest length of %d, instead received %d", 2, len(m))
 	}
 
-	if m["hello"] != "world" {
-		t.Errorf("Expected manifest value of '%s', instead received '%s'", "world", m["hello"])
+	if m["world"].Long != "hello" {
+		t.Errorf("Expected manifest value of '%s', instead received '%s'", "hello", m["world"].Long)
 	}
 
-	if m["bonjour"] != "monde" {
-		t.Errorf("Expected manifest value of '%s', instead received '%s'", "monde", m["bonjour"])
+	if m["monde"].Long != "bonjour" {
+		t.Errorf("Expected manifest value of '%s', instead received '%s'", "bonjour", m["monde"].Long)
+	}
+
+	// clean up
+	clearTestData()
+}
+
+func TestItLogsAnUnparseableManifest(t *testing.T) {
+	// set invalid data
+	setTestData(`not json`)
+
+	buf := bytes.Buffer{}
+	m := loadManifest(getTestDataPath(), slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	if len(m) != 0 {
+		t.Errorf("Expected empty manifest, instead received %+v", m)
+	}
+
+	if !strings.Contains(buf.String(), "Failed to parse manifest") {
+		t.Errorf("Expected parse error to be logged, instead received '%s'", buf.String())
 	}
 
 	// clean up
@@ -256,10 +667,10 @@ func TestItSuccessfullySavesManifest(t *testing.T) {
 	// set initial data
 	setTestData(`{"hello": "world", "bonjour": "monde"}`)
 
-	expectedMap := map[string]string{
-		"goodbye":         "earth",
-		"au revoir":       "terre",
-		"auf wiedersehen": "erde",
+	expectedMap := map[string]record{
+		"earth": {Long: "goodbye"},
+		"terre": {Long: "au revoir", Owner: "key1"},
+		"erde":  {Long: "auf wiedersehen", Owner: "key2"},
 	}
 
 	result := saveManifest(getTestDataPath(), expectedMap)
@@ -267,22 +678,53 @@ func TestItSuccessfullySavesManifest(t *testing.T) {
 		t.Errorf("Expected save manifest to return true, instead returned '%+v'", result)
 	}
 
-	reloaded := loadManifest(getTestDataPath())
+	reloaded := loadManifest(getTestDataPath(), slog.Default())
 
 	if len(reloaded) != 3 {
-		t.Errorf("Expected manifest length of %d, instead received %d", 2, len(reloaded))
+		t.Errorf("Expected manifest length of %d, instead received %d", 3, len(reloaded))
 	}
 
-	if reloaded["goodbye"] != "earth" {
-		t.Errorf("Expected manifest value of '%s', instead received '%s'", "earth", reloaded["goodbye"])
+	for k, v := range expectedMap {
+		if !reflect.DeepEqual(reloaded[k], v) {
+			t.Errorf("Expected manifest value of '%+v', instead received '%+v'", v, reloaded[k])
+		}
 	}
 
-	if reloaded["au revoir"] != "terre" {
-		t.Errorf("Expected manifest value of '%s', instead received '%s'", "terre", reloaded["au revoir"])
+	// clean up
+	clearTestData()
+}
+
+func TestItReadsACompleteManifestWhileItIsSaved(t *testing.T) {
+	// set expected data
+	setTestData(`{"ABC1": {"long": "http://bbc.co.uk"}}`)
+
+	fs := getTestFsRepository()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 50; i++ {
+			fs.RecordClick("ABC1", "")
+		}
+		close(done)
+	}()
+
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+			if _, err := fs.RetrieveByShortCode("ABC1"); err != nil {
+				t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+			}
+		}
 	}
 
-	if reloaded["auf wiedersehen"] != "erde" {
-		t.Errorf("Expected manifest value of '%s', instead received '%s'", "erde", reloaded["auf wiedersehen"])
+	// no temporary files are left behind
+	files, _ := ioutil.ReadDir(fs.basePath)
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".db.txt") {
+			t.Errorf("Expected no temporary files, instead found '%s'", file.Name())
+		}
 	}
 
 	// clean up
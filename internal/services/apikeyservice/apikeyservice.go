@@ -0,0 +1,68 @@
+package apikeyservice
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"http-url-shortener/internal/entities/apikey"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"http-url-shortener/internal/services/authservice"
+	"time"
+)
+
+// keyPrefix identifies secrets issued by this service, e.g. when scanning for leaked credentials
+const keyPrefix = "hus_"
+
+// Mint generates a new API key with the provided scopes, returning its plaintext secret alongside the stored key.
+// The plaintext secret is not stored and cannot be recovered
+func Mint(repo repositoryinterface.APIKeyRepositoryInterface, name string, scopes []string) (string, apikey.APIKey, error) {
+	for _, s := range scopes {
+		if !apikey.IsValidScope(s) {
+			return "", apikey.APIKey{}, fmt.Errorf("Unknown scope '%s'", s)
+		}
+	}
+
+	secret := keyPrefix + base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	k := apikey.New(hex.EncodeToString(randomBytes(8)), name, Hash(secret), scopes, time.Now().UTC())
+
+	created, err := repo.Create(k)
+	if err != nil {
+		return "", apikey.APIKey{}, err
+	}
+
+	return secret, created, nil
+}
+
+// Hash returns the hash of a plaintext API key secret, as stored in the repository
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate resolves a plaintext API key secret to the Principal it belongs to
+func Authenticate(repo repositoryinterface.APIKeyRepositoryInterface, secret string) (authservice.Principal, error) {
+	if secret == "" {
+		return authservice.Principal{}, errors.New("Missing API key")
+	}
+
+	k, err := repo.RetrieveByHash(Hash(secret))
+	if err != nil || k.IsRevoked() {
+		return authservice.Principal{}, errors.New("Invalid API key")
+	}
+
+	return authservice.Principal{
+		ID:     k.GetID(),
+		Name:   k.GetName(),
+		Scopes: k.GetScopes(),
+	}, nil
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return b
+}
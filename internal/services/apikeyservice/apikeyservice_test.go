@@ -0,0 +1,85 @@
+package apikeyservice
+
+import (
+	"http-url-shortener/internal/entities/apikey"
+	"http-url-shortener/internal/repositories/apikeyfilesystemrepository"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestItMintsAnAPIKey(t *testing.T) {
+	repo := getTestRepository()
+	defer clearTestData()
+
+	secret, k, err := Mint(repo, "marketing", []string{apikey.ScopeCreate})
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if !strings.HasPrefix(secret, keyPrefix) {
+		t.Errorf("Expected secret to begin with '%s', instead received '%s'", keyPrefix, secret)
+	}
+
+	if k.GetHash() == secret || k.GetHash() != Hash(secret) {
+		t.Errorf("Expected stored key to hold hash of secret, instead received '%s'", k.GetHash())
+	}
+}
+
+func TestItFailsToMintAnAPIKeyWithAnUnknownScope(t *testing.T) {
+	repo := getTestRepository()
+	defer clearTestData()
+
+	_, _, err := Mint(repo, "marketing", []string{"superuser"})
+	if err == nil || err.Error() != "Unknown scope 'superuser'" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Unknown scope 'superuser'", err)
+	}
+}
+
+func TestItAuthenticatesAnAPIKey(t *testing.T) {
+	repo := getTestRepository()
+	defer clearTestData()
+
+	secret, k, _ := Mint(repo, "marketing", []string{apikey.ScopeCreate})
+
+	p, err := Authenticate(repo, secret)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if p.ID != k.GetID() || p.Name != "marketing" || p.HasScope(apikey.ScopeCreate) != true {
+		t.Errorf("Expected principal for key '%s', instead received '%+v'", k.GetID(), p)
+	}
+}
+
+func TestItFailsToAuthenticateAnUnknownOrRevokedAPIKey(t *testing.T) {
+	repo := getTestRepository()
+	defer clearTestData()
+
+	if _, err := Authenticate(repo, ""); err == nil || err.Error() != "Missing API key" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Missing API key", err)
+	}
+
+	if _, err := Authenticate(repo, "hus_unknown"); err == nil || err.Error() != "Invalid API key" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Invalid API key", err)
+	}
+
+	secret, k, _ := Mint(repo, "marketing", []string{apikey.ScopeCreate})
+	repo.Revoke(k.GetID())
+
+	if _, err := Authenticate(repo, secret); err == nil || err.Error() != "Invalid API key" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Invalid API key", err)
+	}
+}
+
+func clearTestData() {
+	dir, _ := os.Getwd()
+	os.Remove(dir + "/keys.json")
+}
+
+func getTestRepository() apikeyfilesystemrepository.FileSystem {
+	clearTestData()
+	dir, _ := os.Getwd()
+
+	return apikeyfilesystemrepository.New(dir)
+}
@@ -0,0 +1,46 @@
+package authservice
+
+import "context"
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	ID     string
+	Name   string
+	Scopes []string
+}
+
+// HasScope determines whether the Principal has been granted the provided scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAdmin determines whether the Principal may manage every principal's resources
+func (p Principal) IsAdmin() bool {
+	return p.HasScope("admin")
+}
+
+// CanManage determines whether the Principal may manage a resource belonging to owner
+func (p Principal) CanManage(owner string) bool {
+	return p.IsAdmin() || p.ID == owner
+}
+
+// WithPrincipal returns a copy of ctx that carries the provided Principal
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFromContext returns the Principal carried by ctx, and whether there was one
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
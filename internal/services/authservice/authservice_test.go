@@ -0,0 +1,50 @@
+package authservice
+
+import (
+	"context"
+	"testing"
+)
+
+func TestItChecksAPrincipalsScopes(t *testing.T) {
+	p := Principal{ID: "key1", Scopes: []string{"create"}}
+
+	if p.HasScope("create") != true {
+		t.Errorf("Expected principal to have scope '%s'", "create")
+	}
+
+	if p.HasScope("delete") != false {
+		t.Errorf("Expected principal not to have scope '%s'", "delete")
+	}
+}
+
+func TestItChecksWhetherAPrincipalCanManageAResource(t *testing.T) {
+	owner := Principal{ID: "key1", Scopes: []string{"create"}}
+	other := Principal{ID: "key2", Scopes: []string{"create"}}
+	admin := Principal{ID: "key3", Scopes: []string{"admin"}}
+
+	if owner.CanManage("key1") != true {
+		t.Errorf("Expected owner to manage their own resource")
+	}
+
+	if other.CanManage("key1") != false {
+		t.Errorf("Expected principal not to manage another principal's resource")
+	}
+
+	if admin.IsAdmin() != true || admin.CanManage("key1") != true {
+		t.Errorf("Expected admin to manage any resource")
+	}
+}
+
+func TestItCarriesAPrincipalInContext(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	if ok != false {
+		t.Errorf("Expected no principal in empty context")
+	}
+
+	ctx := WithPrincipal(context.Background(), Principal{ID: "key1"})
+
+	p, ok := PrincipalFromContext(ctx)
+	if ok != true || p.ID != "key1" {
+		t.Errorf("Expected principal '%s', instead received '%+v'", "key1", p)
+	}
+}
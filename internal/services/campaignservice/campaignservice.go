@@ -0,0 +1,67 @@
+package campaignservice
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UTM represents the Urchin Tracking Module parameters that attribute visits to a marketing campaign
+type UTM struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// IsEmpty determines whether none of the UTM parameters have been provided
+func (u UTM) IsEmpty() bool {
+	return u == UTM{}
+}
+
+// Apply merges the UTM parameters into the provided URL's query string, replacing any existing values of the same
+// parameters whilst preserving the order and encoding of the others
+func (u UTM) Apply(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	params := []struct{ key, value string }{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+		{"utm_term", u.Term},
+		{"utm_content", u.Content},
+	}
+
+	replaced := map[string]bool{}
+	added := []string{}
+	for _, p := range params {
+		if p.value != "" {
+			replaced[p.key] = true
+			added = append(added, p.key+"="+url.QueryEscape(p.value))
+		}
+	}
+
+	kept := []string{}
+	for _, pair := range strings.Split(parsed.RawQuery, "&") {
+		key, _ := url.QueryUnescape(strings.SplitN(pair, "=", 2)[0])
+		if pair != "" && !replaced[key] {
+			kept = append(kept, pair)
+		}
+	}
+
+	parsed.RawQuery = strings.Join(append(kept, added...), "&")
+	return parsed.String(), nil
+}
+
+// CampaignOf returns the campaign that a URL attributes visits to, via its `utm_campaign` parameter
+func CampaignOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Query().Get("utm_campaign")
+}
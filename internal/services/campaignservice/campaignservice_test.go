@@ -0,0 +1,38 @@
+package campaignservice
+
+import "testing"
+
+func TestItAppliesUTMParametersToAURL(t *testing.T) {
+	utm := UTM{Source: "newsletter", Medium: "email", Campaign: "spring sale"}
+
+	tests := map[string]string{
+		"http://bbc.co.uk": "http://bbc.co.uk?utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale",
+		"http://bbc.co.uk/news?id=1&utm_source=x&q=a%20b": "http://bbc.co.uk/news?id=1&q=a%20b&utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale",
+		"http://bbc.co.uk/news?utm_term=kept#section":     "http://bbc.co.uk/news?utm_term=kept&utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale#section",
+	}
+
+	for raw, expected := range tests {
+		applied, err := utm.Apply(raw)
+		if err != nil {
+			t.Errorf("Not expecting error, instead received '%s'", err.Error())
+		}
+
+		if applied != expected {
+			t.Errorf("Expected '%s' with UTM parameters to be '%s', instead received '%s'", raw, expected, applied)
+		}
+	}
+}
+
+func TestItDeterminesTheCampaignOfAURL(t *testing.T) {
+	if c := CampaignOf("http://bbc.co.uk?utm_campaign=spring+sale"); c != "spring sale" {
+		t.Errorf("Expected campaign of '%s', instead received '%s'", "spring sale", c)
+	}
+
+	if c := CampaignOf("http://bbc.co.uk"); c != "" {
+		t.Errorf("Expected no campaign, instead received '%s'", c)
+	}
+
+	if (UTM{}).IsEmpty() != true || (UTM{Term: "x"}).IsEmpty() != false {
+		t.Errorf("Expected only a UTM without parameters to be empty")
+	}
+}
@@ -0,0 +1,165 @@
+package configservice
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config represents the runtime configuration of the API
+type Config struct {
+	HTTPAddr          string
+	HTTPSAddr         string
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSReloadInterval time.Duration
+	HSTSMaxAge        int
+	LogLevel          string
+	AuthRequired      bool
+	LinkQuota         int
+	JWTJWKS           string
+	JWTJWKSRefresh    time.Duration
+	JWTIssuer         string
+	JWTAudience       string
+	JWTRolesClaim     string
+	ShortenRateLimit  int
+	ShortenBurst      int
+	RedirectRateLimit int
+	RedirectBurst     int
+	APIRateLimit      int
+	APIBurst          int
+	AuthRateLimit     int
+	AuthBurst         int
+	TrustProxy        bool
+	URLSchemes        []string
+	URLMaxLength      int
+	StripTracking     bool
+	DomainAllowlist   []string
+	DomainBlocklist   []string
+	SelfHosts         []string
+	ResolveSelfLinks  bool
+	ThreatList        string
+	QuarantineThreats bool
+	ThreatRescan      time.Duration
+	ParamConflict     string
+	CaseInsensitive   bool
+	Confusables       bool
+	GeoIPDatabase     string
+	UnlockSecret      string
+	UnlockTTL         time.Duration
+	UnlockRateLimit   int
+	UnlockBurst       int
+	InactiveURL       string
+	NotFoundURL       string
+	NotFoundSearchURL string
+	SiteName          string
+	RedirectMaxAge    time.Duration
+}
+
+// Load returns a new Config populated from environment variables, falling back to defaults
+func Load() Config {
+	return Config{
+		HTTPAddr:          getString("HTTP_ADDR", ":8080"),
+		HTTPSAddr:         getString("HTTPS_ADDR", ":8443"),
+		TLSCertFile:       getString("TLS_CERT_FILE", ""),
+		TLSKeyFile:        getString("TLS_KEY_FILE", ""),
+		TLSReloadInterval: getDuration("TLS_RELOAD_INTERVAL", 30*time.Second),
+		HSTSMaxAge:        getInt("HSTS_MAX_AGE", 31536000),
+		LogLevel:          getString("LOG_LEVEL", "info"),
+		AuthRequired:      getBool("AUTH_REQUIRED", true),
+		LinkQuota:         getInt("LINK_QUOTA", 0),
+		JWTJWKS:           getString("JWT_JWKS", ""),
+		JWTJWKSRefresh:    getDuration("JWT_JWKS_REFRESH", time.Hour),
+		JWTIssuer:         getString("JWT_ISSUER", ""),
+		JWTAudience:       getString("JWT_AUDIENCE", ""),
+		JWTRolesClaim:     getString("JWT_ROLES_CLAIM", "roles"),
+		ShortenRateLimit:  getInt("SHORTEN_RATE_LIMIT", 10),
+		ShortenBurst:      getInt("SHORTEN_BURST", 5),
+		RedirectRateLimit: getInt("REDIRECT_RATE_LIMIT", 600),
+		RedirectBurst:     getInt("REDIRECT_BURST", 100),
+		APIRateLimit:      getInt("API_RATE_LIMIT", 120),
+		APIBurst:          getInt("API_BURST", 30),
+		AuthRateLimit:     getInt("AUTH_RATE_LIMIT", 600),
+		AuthBurst:         getInt("AUTH_BURST", 100),
+		TrustProxy:        getBool("TRUST_PROXY", false),
+		URLSchemes:        getList("URL_SCHEMES", []string{"http", "https"}),
+		URLMaxLength:      getInt("URL_MAX_LENGTH", 2048),
+		StripTracking:     getBool("STRIP_TRACKING_PARAMS", false),
+		DomainAllowlist:   getList("DOMAIN_ALLOWLIST", []string{}),
+		DomainBlocklist:   getList("DOMAIN_BLOCKLIST", []string{}),
+		SelfHosts:         getList("SELF_HOSTS", []string{}),
+		ResolveSelfLinks:  getBool("RESOLVE_SELF_LINKS", false),
+		ThreatList:        getString("THREAT_LIST", ""),
+		QuarantineThreats: getBool("QUARANTINE_THREATS", false),
+		ThreatRescan:      getDuration("THREAT_RESCAN_INTERVAL", 0),
+		ParamConflict:     getString("PASSTHROUGH_PARAM_CONFLICT", "destination"),
+		CaseInsensitive:   getBool("SHORT_CODE_CASE_INSENSITIVE", true),
+		Confusables:       getBool("SHORT_CODE_CONFUSABLES", false),
+		GeoIPDatabase:     getString("GEOIP_DATABASE", ""),
+		UnlockSecret:      getString("UNLOCK_SECRET", ""),
+		UnlockTTL:         getDuration("UNLOCK_TTL", time.Hour),
+		UnlockRateLimit:   getInt("UNLOCK_RATE_LIMIT", 5),
+		UnlockBurst:       getInt("UNLOCK_BURST", 5),
+		InactiveURL:       getString("INACTIVE_URL", ""),
+		NotFoundURL:       getString("NOT_FOUND_URL", ""),
+		NotFoundSearchURL: getString("NOT_FOUND_SEARCH_URL", ""),
+		SiteName:          getString("SITE_NAME", ""),
+		RedirectMaxAge:    getDuration("REDIRECT_MAX_AGE", time.Hour),
+	}
+}
+
+// TLSEnabled determines whether both a certificate and key file have been configured
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+func getString(key string, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+
+	return fallback
+}
+
+func getList(key string, fallback []string) []string {
+	values := []string{}
+	for _, v := range strings.Split(getString(key, ""), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	if len(values) == 0 {
+		return fallback
+	}
+
+	return values
+}
+
+func getInt(key string, fallback int) int {
+	v, err := strconv.Atoi(getString(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return v
+}
+
+func getBool(key string, fallback bool) bool {
+	v, err := strconv.ParseBool(getString(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return v
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(getString(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return v
+}
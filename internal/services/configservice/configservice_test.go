@@ -0,0 +1,81 @@
+package configservice
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestItLoadsDefaultConfig(t *testing.T) {
+	os.Unsetenv("HTTP_ADDR")
+	os.Unsetenv("TLS_CERT_FILE")
+	os.Unsetenv("TLS_KEY_FILE")
+	os.Unsetenv("TLS_RELOAD_INTERVAL")
+
+	c := Load()
+
+	if c.HTTPAddr != ":8080" {
+		t.Errorf("Expected HTTP address of '%s', instead received '%s'", ":8080", c.HTTPAddr)
+	}
+
+	if c.TLSReloadInterval != 30*time.Second {
+		t.Errorf("Expected TLS reload interval of %s, instead received %s", 30*time.Second, c.TLSReloadInterval)
+	}
+
+	if c.TLSEnabled() != false {
+		t.Errorf("Expected TLS to be disabled by default")
+	}
+
+	if c.AuthRequired != true {
+		t.Errorf("Expected authentication to be required by default")
+	}
+
+	if c.ShortenRateLimit != 10 || c.ShortenBurst != 5 {
+		t.Errorf("Expected shorten rate limit of %d/min (burst %d), instead received %d/min (burst %d)", 10, 5, c.ShortenRateLimit, c.ShortenBurst)
+	}
+}
+
+func TestItLoadsConfigFromEnvironment(t *testing.T) {
+	os.Setenv("HTTP_ADDR", ":9090")
+	os.Setenv("TLS_CERT_FILE", "/certs/cert.pem")
+	os.Setenv("TLS_KEY_FILE", "/certs/key.pem")
+	os.Setenv("TLS_RELOAD_INTERVAL", "5s")
+	os.Setenv("HSTS_MAX_AGE", "not-a-number")
+	os.Setenv("AUTH_REQUIRED", "false")
+	os.Setenv("URL_SCHEMES", "https, ftp,")
+	defer func() {
+		os.Unsetenv("HTTP_ADDR")
+		os.Unsetenv("TLS_CERT_FILE")
+		os.Unsetenv("TLS_KEY_FILE")
+		os.Unsetenv("TLS_RELOAD_INTERVAL")
+		os.Unsetenv("HSTS_MAX_AGE")
+		os.Unsetenv("AUTH_REQUIRED")
+		os.Unsetenv("URL_SCHEMES")
+	}()
+
+	c := Load()
+
+	if c.HTTPAddr != ":9090" {
+		t.Errorf("Expected HTTP address of '%s', instead received '%s'", ":9090", c.HTTPAddr)
+	}
+
+	if c.TLSReloadInterval != 5*time.Second {
+		t.Errorf("Expected TLS reload interval of %s, instead received %s", 5*time.Second, c.TLSReloadInterval)
+	}
+
+	if c.HSTSMaxAge != 31536000 {
+		t.Errorf("Expected invalid HSTS max age to fall back to %d, instead received %d", 31536000, c.HSTSMaxAge)
+	}
+
+	if c.TLSEnabled() != true {
+		t.Errorf("Expected TLS to be enabled")
+	}
+
+	if c.AuthRequired != false {
+		t.Errorf("Expected authentication not to be required")
+	}
+
+	if len(c.URLSchemes) != 2 || c.URLSchemes[0] != "https" || c.URLSchemes[1] != "ftp" {
+		t.Errorf("Expected URL schemes of '%+v', instead received '%+v'", []string{"https", "ftp"}, c.URLSchemes)
+	}
+}
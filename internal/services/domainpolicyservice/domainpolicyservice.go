@@ -0,0 +1,170 @@
+package domainpolicyservice
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// List represents a set of hosts, matched exactly, by wildcard subdomain (e.g. `*.example.com`) or by CIDR (for IP literals)
+type List struct {
+	hosts     map[string]bool
+	wildcards []string
+	networks  []*net.IPNet
+}
+
+// Policy represents the hosts that destination URLs may and may not point to
+type Policy struct {
+	Allow List
+	Block List
+}
+
+// NewList returns a new List of the provided entries
+func NewList(entries []string) (List, error) {
+	l := List{
+		hosts: map[string]bool{},
+	}
+
+	for _, entry := range entries {
+		entry = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(entry)), ".")
+
+		switch {
+		case entry == "":
+			continue
+
+		case strings.HasPrefix(entry, "*."):
+			l.wildcards = append(l.wildcards, entry[1:])
+
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return List{}, fmt.Errorf("Invalid domain list entry `%s`", entry)
+			}
+
+			l.networks = append(l.networks, network)
+
+		case strings.Contains(entry, "*"):
+			return List{}, fmt.Errorf("Invalid domain list entry `%s`", entry)
+
+		default:
+			if ip := parseIP(entry); ip != nil {
+				entry = ip.String()
+			}
+
+			l.hosts[entry] = true
+		}
+	}
+
+	return l, nil
+}
+
+// IsEmpty determines whether the List has no entries
+func (l List) IsEmpty() bool {
+	return len(l.hosts) == 0 && len(l.wildcards) == 0 && len(l.networks) == 0
+}
+
+// Contains determines whether the provided host matches an entry of the List
+func (l List) Contains(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if ip := parseIP(host); ip != nil {
+		for _, network := range l.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+
+		return l.hosts[ip.String()]
+	}
+
+	if l.hosts[host] {
+		return true
+	}
+
+	for _, suffix := range l.wildcards {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check returns an error explaining why the Policy does not permit the provided URL, if it does not
+func (p Policy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("Destination `%s` is not a valid URL", rawURL)
+	}
+
+	host := u.Hostname()
+
+	if p.Block.Contains(host) {
+		return fmt.Errorf("Destination host `%s` is blocked", host)
+	}
+
+	if !p.Allow.IsEmpty() && !p.Allow.Contains(host) {
+		return fmt.Errorf("Destination host `%s` is not on the allowlist", host)
+	}
+
+	return nil
+}
+
+// parseIP parses an IP literal, including the numeric IPv4 forms that browsers resolve (e.g. `2130706433`,
+// `0x7f000001`, `017700000001` or `127.1` for `127.0.0.1`), so that they can't evade a CIDR entry
+func parseIP(host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+
+	var ip uint64
+	for i, part := range parts {
+		value, ok := parseIPv4Part(part)
+		if !ok {
+			return nil
+		}
+
+		// every part but the last is a single byte, and the last fills the remaining bytes
+		remaining := uint(4 - i)
+		if i < len(parts)-1 {
+			remaining = 1
+		}
+
+		if value >= 1<<(8*remaining) {
+			return nil
+		}
+
+		ip = ip<<(8*remaining) | value
+	}
+
+	return net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip))
+}
+
+// parseIPv4Part parses a part of a numeric IPv4 address, which is hexadecimal if prefixed with `0x`,
+// octal if prefixed with `0`, and otherwise decimal
+func parseIPv4Part(part string) (uint64, bool) {
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x"):
+		part, base = part[2:], 16
+		if part == "" {
+			return 0, true
+		}
+	case len(part) > 1 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+
+	value, err := strconv.ParseUint(part, base, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
@@ -0,0 +1,111 @@
+package domainpolicyservice
+
+import "testing"
+
+func TestItMatchesHostsAgainstAList(t *testing.T) {
+	l, err := NewList([]string{"evil.com", "*.phish.net", "10.0.0.0/8", "2001:DB8::1", ""})
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	tests := map[string]bool{
+		"evil.com":             true,
+		"EVIL.com.":            true,
+		"www.evil.com":         false,
+		"login.phish.net":      true,
+		"a.b.phish.net":        true,
+		"phish.net":            false,
+		"notphish.net":         false,
+		"10.1.2.3":             true,
+		"11.1.2.3":             false,
+		"2001:db8:0:0:0:0:0:1": true,
+		"bbc.co.uk":            false,
+	}
+
+	for host, expected := range tests {
+		if l.Contains(host) != expected {
+			t.Errorf("Expected '%s' match to be %t, instead received %t", host, expected, !expected)
+		}
+	}
+}
+
+func TestItMatchesNumericIPv4HostsAgainstNetworks(t *testing.T) {
+	l, _ := NewList([]string{"127.0.0.0/8", "2130706434"})
+
+	tests := map[string]bool{
+		"2130706433":        true,
+		"0x7f000001":        true,
+		"0X7F000001":        true,
+		"017700000001":      true,
+		"127.1":             true,
+		"0177.0.0.1":        true,
+		"0x7f.0.0.1":        true,
+		"127.0.0.1.":        true,
+		"167772161":         false,
+		"4294967296":        false,
+		"256.0.0.1":         false,
+		"127.0.0.0.1":       false,
+		"0x7f.example.com":  false,
+		"1.example.com":     false,
+		"09.0.0.1":          false,
+		"127.0.0.1_000":     false,
+		"+2130706433":       false,
+		"2130706433.com":    false,
+		"example.127.0.0.1": false,
+	}
+
+	for host, expected := range tests {
+		if l.Contains(host) != expected {
+			t.Errorf("Expected '%s' match to be %t, instead received %t", host, expected, !expected)
+		}
+	}
+
+	// entries are normalised in the same way
+	if l.Contains("127.0.0.2") != true {
+		t.Errorf("Expected '%s' to match the numeric entry '%s'", "127.0.0.2", "2130706434")
+	}
+}
+
+func TestItChecksURLsWithNumericIPv4HostsAgainstAPolicy(t *testing.T) {
+	block, _ := NewList([]string{"127.0.0.0/8"})
+	p := Policy{Block: block}
+
+	for _, u := range []string{"http://2130706433/", "http://0x7f000001/", "http://017700000001/"} {
+		if err := p.Check(u); err == nil {
+			t.Errorf("Expected '%s' to be blocked, instead it was permitted", u)
+		}
+	}
+}
+
+func TestItRejectsInvalidListEntries(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "evil.*.com"} {
+		if _, err := NewList([]string{entry}); err == nil || err.Error() != "Invalid domain list entry `"+entry+"`" {
+			t.Errorf("Expected error for entry '%s', instead received '%v'", entry, err)
+		}
+	}
+}
+
+func TestItChecksURLsAgainstAPolicy(t *testing.T) {
+	allow, _ := NewList([]string{"*.co.uk", "bbc.com"})
+	block, _ := NewList([]string{"evil.co.uk"})
+	p := Policy{Allow: allow, Block: block}
+
+	tests := map[string]string{
+		"http://www.bbc.co.uk/news": "",
+		"https://bbc.com":           "",
+		"http://evil.co.uk":         "Destination host `evil.co.uk` is blocked",
+		"http://example.com":        "Destination host `example.com` is not on the allowlist",
+	}
+
+	for u, expected := range tests {
+		err := p.Check(u)
+		if (expected == "" && err != nil) || (expected != "" && (err == nil || err.Error() != expected)) {
+			t.Errorf("Expected '%s' check to return '%s', instead received '%v'", u, expected, err)
+		}
+	}
+
+	// an empty policy permits everything
+	if err := (Policy{}).Check("http://example.com"); err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+}
@@ -0,0 +1,144 @@
+package geoipservice
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Locator determines the country that an IP address is located in
+type Locator interface {
+	// Country returns the ISO 3166 code of the country that the IP address is located in, or an empty string if unknown
+	Country(ip string) string
+}
+
+// Database represents a Locator backed by a local GeoIP database file of networks and their countries.
+// Each line of the file holds a CIDR network and a country code, separated by whitespace or a comma -
+// e.g. `81.2.69.0/24,GB` - and lines starting with `#` are ignored
+type Database struct {
+	path string
+
+	mu       sync.RWMutex
+	networks map[netip.Prefix]string
+	lengths4 []int
+	lengths6 []int
+}
+
+// NewDatabase returns a new Database loaded from the provided file
+func NewDatabase(path string) (*Database, error) {
+	d := &Database{path: path}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Reload re-reads the database file, retaining the previous networks if it cannot be read
+func (d *Database) Reload() error {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	networks := map[netip.Prefix]string{}
+	lengths4 := map[int]bool{}
+	lengths6 := map[int]bool{}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) != 2 {
+			return fmt.Errorf("Malformed GeoIP entry on line %d", n)
+		}
+
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return fmt.Errorf("Malformed GeoIP network on line %d", n)
+		}
+
+		prefix = prefix.Masked()
+		networks[prefix] = strings.ToUpper(fields[1])
+
+		if prefix.Addr().Is4() {
+			lengths4[prefix.Bits()] = true
+		} else {
+			lengths6[prefix.Bits()] = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.networks = networks
+	d.lengths4 = descending(lengths4)
+	d.lengths6 = descending(lengths6)
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Country returns the country of the most specific network containing the IP address
+func (d *Database) Country(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	lengths := d.lengths4
+	if addr.Is6() {
+		lengths = d.lengths6
+	}
+
+	for _, length := range lengths {
+		prefix, err := addr.Prefix(length)
+		if err != nil {
+			continue
+		}
+
+		if country, ok := d.networks[prefix]; ok {
+			return country
+		}
+	}
+
+	return ""
+}
+
+// descending returns the prefix lengths in a set, longest first, so that the most specific networks are looked up first
+func descending(lengths map[int]bool) []int {
+	sorted := []int{}
+	for length := range lengths {
+		sorted = append(sorted, length)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	return sorted
+}
+
+// Mock represents a Locator that locates IP addresses from a fixed map of IP address to country, for use in tests
+type Mock struct {
+	Countries map[string]string
+}
+
+// Country returns the country mapped to the provided IP address
+func (m Mock) Country(ip string) string {
+	return m.Countries[ip]
+}
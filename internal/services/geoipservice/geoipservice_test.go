@@ -0,0 +1,44 @@
+package geoipservice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestItLocatesTheCountryOfAnIPAddress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geoip.csv")
+	os.WriteFile(path, []byte("# network,country\n81.2.0.0/16,gb\n81.2.69.0/24,FR\n2001:db8::/32 DE\n"), 0644)
+
+	d, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	tests := map[string]string{
+		"81.2.1.1":          "GB",
+		"81.2.69.160":       "FR",
+		"::ffff:81.2.69.1":  "FR",
+		"2001:db8::1":       "DE",
+		"8.8.8.8":           "",
+		"not an ip address": "",
+	}
+
+	for ip, expected := range tests {
+		if country := d.Country(ip); country != expected {
+			t.Errorf("Expected country of '%s' to be '%s', instead received '%s'", ip, expected, country)
+		}
+	}
+}
+
+func TestItFailsToLoadAMalformedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geoip.csv")
+	os.WriteFile(path, []byte("81.2.0.0/16,GB\nnot-a-network,FR\n"), 0644)
+
+	_, err := NewDatabase(path)
+
+	expectedError := "Malformed GeoIP network on line 2"
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error '%s', instead received '%v'", expectedError, err)
+	}
+}
@@ -0,0 +1,188 @@
+package jwtservice
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval prevents tokens bearing unknown key IDs from forcing constant refetches
+const minRefreshInterval = time.Minute
+
+// KeySet represents a JSON Web Key Set loaded from a local file or URL, cached and periodically refreshed
+type KeySet struct {
+	source string
+	ttl    time.Duration
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// jwk represents a single JSON Web Key
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewKeySet returns a new KeySet that loads keys from source (a file path or http(s) URL), refreshing them after ttl
+func NewKeySet(source string, ttl time.Duration) *KeySet {
+	return &KeySet{
+		source: source,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+		keys:   map[string]crypto.PublicKey{},
+	}
+}
+
+// Key returns the public key identified by kid, refreshing the key set if it has expired or the key is unknown
+func (k *KeySet) Key(kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	expired := now.Sub(k.fetchedAt) >= k.ttl
+	_, known := k.keys[kid]
+
+	// refresh on expiry, or on an unknown key (which may indicate the keys have been rotated)
+	if (expired || !known) && now.Sub(k.attemptedAt) >= minRefreshInterval {
+		k.attemptedAt = now
+
+		keys, err := k.fetch()
+		if err != nil && len(k.keys) == 0 {
+			return nil, err
+		}
+
+		// retain previous keys if the refresh failed
+		if err == nil {
+			k.keys = keys
+			k.fetchedAt = now
+		}
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("Unknown signing key '%s'", kid)
+	}
+
+	return key, nil
+}
+
+func (k *KeySet) fetch() (map[string]crypto.PublicKey, error) {
+	contents, err := k.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(contents, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+
+		// skip keys of unsupported types or curves, which the provider may publish alongside those we can verify
+		key, err := j.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[j.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("Key set contains no supported signing keys")
+	}
+
+	return keys, nil
+}
+
+func (k *KeySet) read() ([]byte, error) {
+	if !strings.HasPrefix(k.source, "http://") && !strings.HasPrefix(k.source, "https://") {
+		return ioutil.ReadFile(k.source)
+	}
+
+	resp, err := k.client.Get(k.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status %d fetching key set", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("Unsupported curve '%s'", j.Crv)
+		}
+
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, errors.New("Unsupported key type '" + j.Kty + "'")
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
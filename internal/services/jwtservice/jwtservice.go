@@ -0,0 +1,214 @@
+package jwtservice
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http-url-shortener/internal/services/authservice"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// leeway tolerates clock skew between the token issuer and this service
+const leeway = time.Minute
+
+// Validator verifies bearer tokens against a KeySet and maps their claims to a Principal
+type Validator struct {
+	keys       *KeySet
+	issuer     string
+	audience   string
+	rolesClaim string
+	now        func() time.Time
+}
+
+// Claims represents the payload of a verified token
+type Claims map[string]interface{}
+
+// New returns a new Validator. An empty issuer or audience is not checked
+func New(keys *KeySet, issuer string, audience string, rolesClaim string) Validator {
+	return Validator{
+		keys:       keys,
+		issuer:     issuer,
+		audience:   audience,
+		rolesClaim: rolesClaim,
+		now:        time.Now,
+	}
+}
+
+// PrincipalPrefix namespaces the IDs of principals identified by a token's subject, which can then never equal
+// the (hex encoded) ID of an API key, so that a token can't be issued for a subject that owns an API key's links
+const PrincipalPrefix = "jwt:"
+
+// Authenticate verifies the provided token, returning the Principal its claims identify
+func (v Validator) Authenticate(token string) (authservice.Principal, error) {
+	claims, err := v.Verify(token)
+	if err != nil {
+		return authservice.Principal{}, err
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return authservice.Principal{}, errors.New("Token is missing subject")
+	}
+
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name, _ = claims["email"].(string)
+	}
+
+	return authservice.Principal{
+		ID:     PrincipalPrefix + sub,
+		Name:   name,
+		Scopes: claims.strings(v.rolesClaim),
+	}, nil
+}
+
+// Verify checks the token's signature and registered claims, returning its claims
+func (v Validator) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("Malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("Malformed token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("Malformed token signature")
+	}
+
+	key, err := v.keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claims := Claims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("Malformed token claims")
+	}
+
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v Validator) checkClaims(claims Claims) error {
+	now := v.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("Token is missing expiry")
+	}
+
+	if now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return errors.New("Token has expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("Token is not yet valid")
+	}
+
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return errors.New("Token has unexpected issuer")
+	}
+
+	if v.audience != "" && !contains(claims.strings("aud"), v.audience) {
+		return errors.New("Token has unexpected audience")
+	}
+
+	return nil
+}
+
+// strings returns a claim that may be a single string, a space separated string or an array of strings
+func (c Claims) strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := []string{}
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+
+		return values
+	}
+
+	return []string{}
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	hashed := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("Signing key does not match algorithm")
+		}
+
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], signature) != nil {
+			return errors.New("Invalid token signature")
+		}
+
+		return nil
+
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("Signing key does not match algorithm")
+		}
+
+		if len(signature) != 64 {
+			return errors.New("Invalid token signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, hashed[:], r, s) {
+			return errors.New("Invalid token signature")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("Unsupported algorithm '%s'", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
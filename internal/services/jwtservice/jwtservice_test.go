@@ -0,0 +1,284 @@
+package jwtservice
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+var ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+func TestItAuthenticatesAnRS256Token(t *testing.T) {
+	v := getTestValidator(t, getTestJWKS("rsa1"))
+
+	token := signRS256("rsa1", map[string]interface{}{
+		"sub":   "user1",
+		"name":  "Ada",
+		"iss":   "https://sso.example",
+		"aud":   []string{"shortener"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"create", "admin"},
+	})
+
+	p, err := v.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if p.ID != "jwt:user1" || p.Name != "Ada" {
+		t.Errorf("Expected principal 'jwt:user1' (Ada), instead received '%+v'", p)
+	}
+
+	if p.HasScope("create") != true || p.IsAdmin() != true {
+		t.Errorf("Expected principal with create and admin roles, instead received '%+v'", p.Scopes)
+	}
+}
+
+func TestItAuthenticatesAnES256Token(t *testing.T) {
+	v := getTestValidator(t, getTestJWKS("ec1"))
+
+	token := signES256("ec1", map[string]interface{}{
+		"sub":   "user2",
+		"iss":   "https://sso.example",
+		"aud":   "shortener",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": "create read-stats",
+	})
+
+	p, err := v.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if p.ID != "jwt:user2" || p.HasScope("read-stats") != true {
+		t.Errorf("Expected principal 'jwt:user2' with read-stats role, instead received '%+v'", p)
+	}
+}
+
+func TestItNamespacesPrincipalsAwayFromAPIKeys(t *testing.T) {
+	v := getTestValidator(t, getTestJWKS("rsa1"))
+
+	// a subject that is also the ID of an API key
+	apiKeyID := "0123456789abcdef"
+
+	token := signRS256("rsa1", map[string]interface{}{
+		"sub": apiKeyID,
+		"iss": "https://sso.example",
+		"aud": "shortener",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	p, err := v.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if p.ID == apiKeyID || p.CanManage(apiKeyID) {
+		t.Errorf("Expected principal to not manage the links of API key '%s', instead received '%+v'", apiKeyID, p)
+	}
+
+	if p.ID != "jwt:"+apiKeyID {
+		t.Errorf("Expected principal 'jwt:%s', instead received '%s'", apiKeyID, p.ID)
+	}
+}
+
+func TestItRejectsInvalidTokens(t *testing.T) {
+	v := getTestValidator(t, getTestJWKS("rsa1"))
+
+	valid := map[string]interface{}{
+		"sub": "user1",
+		"iss": "https://sso.example",
+		"aud": "shortener",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	tests := map[string]string{
+		"Malformed token":               "not-a-token",
+		"Token has expired":             signRS256("rsa1", withClaim(valid, "exp", time.Now().Add(-time.Hour).Unix())),
+		"Token is not yet valid":        signRS256("rsa1", withClaim(valid, "nbf", time.Now().Add(time.Hour).Unix())),
+		"Token is missing expiry":       signRS256("rsa1", withClaim(valid, "exp", nil)),
+		"Token has unexpected issuer":   signRS256("rsa1", withClaim(valid, "iss", "https://evil.example")),
+		"Token has unexpected audience": signRS256("rsa1", withClaim(valid, "aud", "other")),
+		"Token is missing subject":      signRS256("rsa1", withClaim(valid, "sub", nil)),
+		"Unknown signing key 'rsa2'":    signRS256("rsa2", valid),
+		"Unsupported algorithm 'none'":  encodeSegment(map[string]string{"alg": "none", "kid": "rsa1"}) + "." + encodeSegment(valid) + ".",
+	}
+
+	for expected, token := range tests {
+		_, err := v.Authenticate(token)
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected error '%s', instead received '%v'", expected, err)
+		}
+	}
+
+	// tamper with a valid token's claims
+	parts := strings.Split(signRS256("rsa1", valid), ".")
+	parts[1] = encodeSegment(withClaim(valid, "sub", "admin"))
+
+	if _, err := v.Authenticate(strings.Join(parts, ".")); err == nil || err.Error() != "Invalid token signature" {
+		t.Errorf("Expected error '%s', instead received '%v'", "Invalid token signature", err)
+	}
+}
+
+func TestItRefreshesAKeySetFromAURLWhenKeysAreRotated(t *testing.T) {
+	var jwks atomic.Value
+	jwks.Store(getTestJWKS("rsa1"))
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write([]byte(jwks.Load().(string)))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	keys := NewKeySet(server.URL, time.Hour)
+	keys.now = func() time.Time { return now }
+
+	if _, err := keys.Key("rsa1"); err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	// cached whilst fresh
+	keys.Key("rsa1")
+	if atomic.LoadInt32(&fetches) != 1 {
+		t.Errorf("Expected %d fetch, instead received %d", 1, fetches)
+	}
+
+	// rotate keys, and allow a refresh
+	jwks.Store(getTestJWKS("ec1"))
+	now = now.Add(2 * minRefreshInterval)
+
+	if _, err := keys.Key("ec1"); err != nil {
+		t.Errorf("Expected rotated key to be found, instead received '%s'", err.Error())
+	}
+
+	if atomic.LoadInt32(&fetches) != 2 {
+		t.Errorf("Expected %d fetches, instead received %d", 2, fetches)
+	}
+}
+
+func TestItSkipsUnsupportedKeysInAKeySet(t *testing.T) {
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	json.Unmarshal([]byte(getTestJWKS("rsa1")), &set)
+
+	unsupported := []map[string]string{
+		{"kid": "ec384", "kty": "EC", "crv": "P-384", "x": "AA", "y": "AA"},
+		{"kid": "ed1", "kty": "OKP", "crv": "Ed25519", "x": "AA"},
+		{"kid": "enc1", "kty": "RSA", "use": "enc", "n": "AA", "e": "AQAB"},
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"keys": append(unsupported, set.Keys...)})
+	v := getTestValidator(t, string(b))
+
+	token := signRS256("rsa1", map[string]interface{}{
+		"sub": "user1",
+		"iss": "https://sso.example",
+		"aud": "shortener",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	if _, err := v.Authenticate(token); err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	// a key set with no supported keys fails to load
+	b, _ = json.Marshal(map[string]interface{}{"keys": unsupported})
+	keys := getTestValidator(t, string(b)).keys
+
+	if _, err := keys.Key("ec384"); err == nil || err.Error() != "Key set contains no supported signing keys" {
+		t.Errorf("Expected error message of '%s', instead received '%v'", "Key set contains no supported signing keys", err)
+	}
+}
+
+func getTestValidator(t *testing.T, jwks string) Validator {
+	f, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatalf("Unable to create temp file: %s", err.Error())
+	}
+
+	f.Write([]byte(jwks))
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	return New(NewKeySet(f.Name(), time.Hour), "https://sso.example", "shortener", "roles")
+}
+
+func getTestJWKS(kid string) string {
+	keys := []map[string]string{}
+
+	if strings.HasPrefix(kid, "rsa") {
+		keys = append(keys, map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		})
+	} else {
+		keys = append(keys, map[string]string{
+			"kid": kid,
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return string(b)
+}
+
+func signRS256(kid string, claims map[string]interface{}) string {
+	input := encodeSegment(map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(claims)
+	hashed := sha256.Sum256([]byte(input))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, hashed[:])
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func signES256(kid string, claims map[string]interface{}) string {
+	input := encodeSegment(map[string]string{"alg": "ES256", "kid": kid}) + "." + encodeSegment(claims)
+	hashed := sha256.Sum256([]byte(input))
+	r, s, _ := ecdsa.Sign(rand.Reader, ecKey, hashed[:])
+
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func encodeSegment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func withClaim(claims map[string]interface{}, name string, value interface{}) map[string]interface{} {
+	copied := map[string]interface{}{}
+	for k, v := range claims {
+		copied[k] = v
+	}
+
+	if value == nil {
+		delete(copied, name)
+	} else {
+		copied[name] = value
+	}
+
+	return copied
+}
@@ -0,0 +1,62 @@
+package logservice
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestItLogsAtOrAboveTheConfiguredLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := New(&buf, "warn")
+
+	logger.Info("ignored")
+	logger.Warn("logged", "key", "value")
+
+	output := buf.String()
+
+	if strings.Contains(output, "ignored") {
+		t.Errorf("Expected info record to be dropped, instead received '%s'", output)
+	}
+
+	if !strings.Contains(output, `"msg":"logged"`) || !strings.Contains(output, `"key":"value"`) {
+		t.Errorf("Expected warn record to be logged as JSON, instead received '%s'", output)
+	}
+}
+
+func TestItGeneratesUniqueRequestIDs(t *testing.T) {
+	first := NewRequestID()
+	second := NewRequestID()
+
+	if len(first) != 16 {
+		t.Errorf("Expected request ID of length %d, instead received %d", 16, len(first))
+	}
+
+	if first == second {
+		t.Errorf("Expected unique request IDs, instead received '%s' twice", first)
+	}
+}
+
+func TestItCarriesLoggerAndRequestIDInContext(t *testing.T) {
+	ctx := context.Background()
+
+	if RequestID(ctx) != "" {
+		t.Errorf("Expected empty request ID, instead received '%s'", RequestID(ctx))
+	}
+
+	if FromContext(ctx) == nil {
+		t.Errorf("Expected default logger, instead received nil")
+	}
+
+	logger := Discard()
+	ctx = WithLogger(WithRequestID(ctx, "abc123"), logger)
+
+	if RequestID(ctx) != "abc123" {
+		t.Errorf("Expected request ID of '%s', instead received '%s'", "abc123", RequestID(ctx))
+	}
+
+	if FromContext(ctx) != logger {
+		t.Errorf("Expected logger from context to match")
+	}
+}
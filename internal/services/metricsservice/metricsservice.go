@@ -0,0 +1,278 @@
+package metricsservice
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default histogram buckets, in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultRegistry is the registry exposed by the API's metrics endpoint
+var DefaultRegistry = NewRegistry()
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry represents a collection of metrics that can be exposed in Prometheus text format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry returns a new empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounter registers and returns a new Counter
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		desc:   newDesc(name, help, "counter", labelNames),
+		values: map[string]float64{},
+	}
+
+	r.register(c)
+	return c
+}
+
+// NewHistogram registers and returns a new Histogram
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{
+		desc:    newDesc(name, help, "histogram", labelNames),
+		buckets: buckets,
+		series:  map[string]*histogramSeries{},
+	}
+
+	r.register(h)
+	return h
+}
+
+// NewGaugeFunc registers a new gauge whose value is determined by fn at collection time
+func (r *Registry) NewGaugeFunc(name string, help string, fn func() float64) {
+	r.register(&gaugeFunc{
+		desc: newDesc(name, help, "gauge", nil),
+		fn:   fn,
+	})
+}
+
+// Write writes all registered metrics to w in Prometheus text format
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// ServeHTTP exposes the registry's metrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.Write(w)
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	r.metrics = append(r.metrics, m)
+	r.mu.Unlock()
+}
+
+// Counter represents a monotonically increasing value, partitioned by label values
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Inc increments the counter for the provided label values by 1
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter for the provided label values by v
+func (c *Counter) Add(v float64, labelValues ...string) {
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of the counter for the provided label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.values[c.key(labelValues)]
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labels(key, "", ""), formatFloat(c.values[key]))
+	}
+}
+
+// Histogram represents observations counted into buckets, partitioned by label values
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe records v against the histogram for the provided label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations recorded for the provided label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s, ok := h.series[h.key(labelValues)]; ok {
+		return s.count
+	}
+
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h.writeHeader(w)
+	for _, key := range keys {
+		s := h.series[key]
+
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(key, "le", formatFloat(upper)), s.counts[i])
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labels(key, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labels(key, "", ""), s.count)
+	}
+}
+
+type gaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	g.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// desc describes a metric's name, type and labels
+type desc struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+}
+
+func newDesc(name string, help string, metricType string, labelNames []string) desc {
+	return desc{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+	}
+}
+
+func (d desc) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, d.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.metricType)
+}
+
+// key joins label values into a single map key, padding or truncating to the number of label names
+func (d desc) key(labelValues []string) string {
+	values := make([]string, len(d.labelNames))
+	copy(values, labelValues)
+
+	return strings.Join(values, "\xff")
+}
+
+// labels renders the label set for the provided key, with an optional extra label
+func (d desc) labels(key string, extraName string, extraValue string) string {
+	pairs := []string{}
+
+	if len(d.labelNames) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", d.labelNames[i], strconv.Quote(v)))
+		}
+	}
+
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", extraName, strconv.Quote(extraValue)))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,76 @@
+package metricsservice
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestItIncrementsACounterPerLabelSet(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_total", "Test counter", "route")
+
+	c.Inc("/a")
+	c.Inc("/a")
+	c.Add(3, "/b")
+
+	if c.Value("/a") != 2 {
+		t.Errorf("Expected counter value of %d, instead received %f", 2, c.Value("/a"))
+	}
+
+	if c.Value("/b") != 3 {
+		t.Errorf("Expected counter value of %d, instead received %f", 3, c.Value("/b"))
+	}
+}
+
+func TestItWritesMetricsInPrometheusFormat(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.NewCounter("test_total", "Test counter", "route", "status")
+	c.Inc("/api/shorten", "200")
+
+	h := r.NewHistogram("test_seconds", "Test histogram", []float64{0.1, 1}, "route")
+	h.Observe(0.5, "/api/shorten")
+
+	r.NewGaugeFunc("test_links", "Test gauge", func() float64 { return 42 })
+
+	buf := bytes.Buffer{}
+	r.Write(&buf)
+	output := buf.String()
+
+	expectedLines := []string{
+		"# TYPE test_total counter",
+		`test_total{route="/api/shorten",status="200"} 1`,
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{route="/api/shorten",le="0.1"} 0`,
+		`test_seconds_bucket{route="/api/shorten",le="1"} 1`,
+		`test_seconds_bucket{route="/api/shorten",le="+Inf"} 1`,
+		`test_seconds_sum{route="/api/shorten"} 0.5`,
+		`test_seconds_count{route="/api/shorten"} 1`,
+		"# TYPE test_links gauge",
+		"test_links 42",
+	}
+
+	for _, line := range expectedLines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected output to contain '%s', instead received:\n%s", line, output)
+		}
+	}
+}
+
+func TestItServesMetricsOverHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test counter").Inc()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:8080/metrics", nil))
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected plain text content type, instead received '%s'", w.Header().Get("Content-Type"))
+	}
+
+	if !strings.Contains(w.Body.String(), "test_total 1\n") {
+		t.Errorf("Expected body to contain counter, instead received '%s'", w.Body.String())
+	}
+}
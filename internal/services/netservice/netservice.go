@@ -0,0 +1,25 @@
+package netservice
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the IP address of the client, preferring the last X-Forwarded-For address if proxies are trusted.
+// Only the last address was added by the trusted proxy - any before it were sent by the client, which may spoof them
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if values := r.Header.Values("X-Forwarded-For"); trustProxy && len(values) > 0 {
+		addresses := strings.Split(values[len(values)-1], ",")
+		if last := strings.TrimSpace(addresses[len(addresses)-1]); last != "" {
+			return last
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
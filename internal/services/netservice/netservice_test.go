@@ -0,0 +1,38 @@
+package netservice
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestItIdentifiesClientsByTheirConnectingIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+	// the header is ignored unless proxies are trusted
+	if ip := ClientIP(r, false); ip != "192.0.2.1" {
+		t.Errorf("Expected IP '%s', instead received '%s'", "192.0.2.1", ip)
+	}
+}
+
+func TestItIdentifiesClientsByTheAddressAddedByTheTrustedProxy(t *testing.T) {
+	tests := map[string][]string{
+		"203.0.113.9": {"203.0.113.9"},
+		"203.0.113.8": {"1.2.3.4, 203.0.113.8"},
+		"203.0.113.7": {"1.2.3.4", "5.6.7.8, 203.0.113.7"},
+	}
+
+	for expected, forwarded := range tests {
+		r := httptest.NewRequest("GET", "http://localhost:8080/ABC1", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		for _, value := range forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+
+		// addresses spoofed by the client before the proxy's own are ignored
+		if ip := ClientIP(r, true); ip != expected {
+			t.Errorf("Expected IP '%s' for '%v', instead received '%s'", expected, forwarded, ip)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pageservice
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"time"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+// WarningPage represents the data rendered by the warning page
+type WarningPage struct {
+	ShortURL    string
+	Destination string
+	Threat      string
+}
+
+// PreviewPage represents the data rendered by the preview page
+type PreviewPage struct {
+	ShortURL     string
+	Destination  string
+	Title        string
+	CreatedAt    time.Time
+	Clicks       int
+	Interstitial bool
+}
+
+// PasswordPage represents the data rendered by the password page
+type PasswordPage struct {
+	ShortURL string
+	Action   string
+	Error    string
+}
+
+// InactivePage represents the data rendered by the inactive page
+type InactivePage struct {
+	ShortURL    string
+	ActiveFrom  time.Time
+	ActiveUntil time.Time
+	Upcoming    bool
+}
+
+// RenderInactive renders the page shown in place of redirecting, before a link's launch or after its expiry
+func RenderInactive(page InactivePage) (string, error) {
+	return render("inactive.html", page)
+}
+
+// NotFoundPage represents the data rendered by the not found page
+type NotFoundPage struct {
+	URL       string
+	SiteName  string
+	SearchURL string
+}
+
+// RenderNotFound renders the page shown in place of redirecting, for a short code that does not exist
+func RenderNotFound(page NotFoundPage) (string, error) {
+	return render("notfound.html", page)
+}
+
+// RenderPassword renders the form that asks for the password of a protected link
+func RenderPassword(page PasswordPage) (string, error) {
+	return render("password.html", page)
+}
+
+// RenderPreview renders the page that describes where a link goes, without redirecting to it
+func RenderPreview(page PreviewPage) (string, error) {
+	return render("preview.html", page)
+}
+
+// RenderWarning renders the interstitial page shown in place of redirecting to a destination flagged as a threat
+func RenderWarning(page WarningPage) (string, error) {
+	return render("warning.html", page)
+}
+
+func render(name string, data interface{}) (string, error) {
+	t, err := template.ParseFS(templates, "templates/layout.html", "templates/"+name)
+	if err != nil {
+		return "", err
+	}
+
+	buf := bytes.Buffer{}
+	if err := t.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
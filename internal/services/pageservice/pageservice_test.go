@@ -0,0 +1,123 @@
+package pageservice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItRendersAnEscapedWarningPage(t *testing.T) {
+	html, err := RenderWarning(WarningPage{
+		ShortURL:    "http://localhost:8080/ABC1",
+		Destination: "http://evil.example/<script>",
+		Threat:      "phishing",
+	})
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if !strings.Contains(html, "flagged as <strong>phishing</strong>") {
+		t.Errorf("Expected threat type to be rendered, instead received '%s'", html)
+	}
+
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "http://evil.example/&lt;script&gt;") {
+		t.Errorf("Expected destination to be escaped, instead received '%s'", html)
+	}
+}
+
+func TestItRendersAPreviewPage(t *testing.T) {
+	html, err := RenderPreview(PreviewPage{
+		ShortURL:    "http://localhost:8080/ABC1",
+		Destination: "http://bbc.co.uk/news",
+		Title:       "BBC News",
+		CreatedAt:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Clicks:      1,
+	})
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	expected := []string{
+		"<h1>BBC News</h1>",
+		"Created 2 January 2020",
+		"Followed 1 time</li>",
+		`<a href="http://bbc.co.uk/news"`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(html, e) {
+			t.Errorf("Expected page to contain '%s', instead received '%s'", e, html)
+		}
+	}
+}
+
+func TestItDoesNotLinkToAnUnsafeDestination(t *testing.T) {
+	html, _ := RenderPreview(PreviewPage{
+		ShortURL:    "http://localhost:8080/ABC1",
+		Destination: "javascript:alert(1)",
+	})
+
+	if strings.Contains(html, `href="javascript:`) {
+		t.Errorf("Expected unsafe destination not to be linked, instead received '%s'", html)
+	}
+}
+
+func TestItRendersAPasswordPage(t *testing.T) {
+	html, err := RenderPassword(PasswordPage{
+		ShortURL: "http://localhost:8080/ABC1",
+		Action:   "/ABC1?ref=<mail>",
+		Error:    "Incorrect password",
+	})
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	expected := []string{
+		`<form method="post" action="/ABC1?ref=%3cmail%3e">`,
+		`<p class="warning">Incorrect password</p>`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(html, e) {
+			t.Errorf("Expected '%s' to be rendered, instead received '%s'", e, html)
+		}
+	}
+}
+
+func TestItRendersAnInactivePage(t *testing.T) {
+	tests := map[bool]string{
+		true:  "can be followed from 1 January 2026 at 00:00 UTC",
+		false: "could be followed until 1 February 2026 at 00:00 UTC",
+	}
+
+	for upcoming, expected := range tests {
+		html, err := RenderInactive(InactivePage{
+			ShortURL:    "http://localhost:8080/ABC1",
+			ActiveFrom:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			ActiveUntil: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			Upcoming:    upcoming,
+		})
+		if err != nil {
+			t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+		}
+
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected '%s' to be rendered, instead received '%s'", expected, html)
+		}
+	}
+}
+
+func TestItRendersANotFoundPage(t *testing.T) {
+	html, err := RenderNotFound(NotFoundPage{URL: "http://localhost:8080/<ABC1>"})
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if !strings.Contains(html, "http://localhost:8080/&lt;ABC1&gt;") {
+		t.Errorf("Expected escaped URL to be rendered, instead received '%s'", html)
+	}
+
+	if strings.Contains(html, "<form") {
+		t.Errorf("Expected no search box without a search URL, instead received '%s'", html)
+	}
+}
@@ -0,0 +1,111 @@
+package passwordservice
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// iterations is the PBKDF2 work factor of new hashes, as recommended by OWASP for HMAC-SHA256
+const iterations = 600000
+
+// scheme identifies the algorithm of a hash, so that it can be changed without invalidating existing hashes
+const scheme = "pbkdf2-sha256"
+
+// Hash returns a salted PBKDF2-HMAC-SHA256 hash of a password, encoded with its parameters as `pbkdf2-sha256$iterations$salt$key`
+func Hash(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("Password must not be empty")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	return encode(iterations, salt, pbkdf2([]byte(password), salt, iterations, sha256.Size)), nil
+}
+
+// Verify determines whether a password matches a hash returned by Hash, in constant time
+func Verify(password string, hash string) bool {
+	iter, salt, key, err := decode(hash)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(pbkdf2([]byte(password), salt, iter, len(key)), key) == 1
+}
+
+func encode(iter int, salt []byte, key []byte) string {
+	return fmt.Sprintf(
+		"%s$%d$%s$%s",
+		scheme,
+		iter,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decode(hash string) (int, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != scheme {
+		return 0, nil, nil, errors.New("Unknown password hash scheme")
+	}
+
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter < 1 {
+		return 0, nil, nil, errors.New("Malformed password hash")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return 0, nil, nil, errors.New("Malformed password hash")
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return 0, nil, nil, errors.New("Malformed password hash")
+	}
+
+	return iter, salt, key, nil
+}
+
+// pbkdf2 derives a key from a password, as defined by RFC 8018, using HMAC-SHA256 as the pseudorandom function
+func pbkdf2(password []byte, salt []byte, iter int, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	blocks := (keyLen + prf.Size() - 1) / prf.Size()
+
+	key := make([]byte, 0, blocks*prf.Size())
+	u := make([]byte, prf.Size())
+	counter := make([]byte, 4)
+
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter, uint32(block))
+		prf.Write(counter)
+
+		key = prf.Sum(key)
+		t := key[len(key)-prf.Size():]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+
+	return key[:keyLen]
+}
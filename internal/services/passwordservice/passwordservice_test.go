@@ -0,0 +1,65 @@
+package passwordservice
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItDerivesKeysAsDefinedByRFC7914(t *testing.T) {
+	key := pbkdf2([]byte("passwd"), []byte("salt"), 1, 64)
+
+	expected := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if hex.EncodeToString(key) != expected {
+		t.Errorf("Expected key '%s', instead received '%s'", expected, hex.EncodeToString(key))
+	}
+}
+
+func TestItVerifiesAHashedPassword(t *testing.T) {
+	hash, err := Hash("open sesame")
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$600000$") {
+		t.Errorf("Expected hash to describe its parameters, instead received '%s'", hash)
+	}
+
+	if Verify("open sesame", hash) != true {
+		t.Errorf("Expected password to match its hash")
+	}
+
+	if Verify("open sesame!", hash) != false || Verify("open sesame", "not a hash") != false {
+		t.Errorf("Expected only the password to match its hash")
+	}
+
+	if _, err := Hash(""); err == nil {
+		t.Errorf("Expected an empty password to be rejected")
+	}
+}
+
+func TestItValidatesUnlockTokens(t *testing.T) {
+	u := Unlocker{Key: []byte("secret"), TTL: time.Hour}
+	now := time.Unix(1700000000, 0)
+
+	token := u.Token("ABC1", "hash", now)
+
+	if u.Valid(token, "ABC1", "hash", now.Add(59*time.Minute)) != true {
+		t.Errorf("Expected token to be valid before it expires")
+	}
+
+	invalid := map[string]bool{
+		"expired":          u.Valid(token, "ABC1", "hash", now.Add(time.Hour)),
+		"another link":     u.Valid(token, "DEF2", "hash", now),
+		"changed password": u.Valid(token, "ABC1", "rehashed", now),
+		"another key":      Unlocker{Key: []byte("other")}.Valid(token, "ABC1", "hash", now),
+		"extended expiry":  u.Valid("1800000000"+token[10:], "ABC1", "hash", now),
+	}
+
+	for name, valid := range invalid {
+		if valid {
+			t.Errorf("Expected token for %s to be invalid", name)
+		}
+	}
+}
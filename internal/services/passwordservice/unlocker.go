@@ -0,0 +1,48 @@
+package passwordservice
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unlocker issues and validates signed, short-lived tokens proving that a visitor has entered a link's password
+type Unlocker struct {
+	// Key signs tokens, and must be kept secret
+	Key []byte
+
+	// TTL is how long a token remains valid after it is issued
+	TTL time.Duration
+}
+
+// Token returns a token unlocking the link with the provided short code and password hash, valid until the TTL has elapsed.
+// Tokens are bound to the password hash, so that changing a link's password revokes every token issued for it
+func (u Unlocker) Token(shortCode string, passwordHash string, now time.Time) string {
+	expires := strconv.FormatInt(now.Add(u.TTL).Unix(), 10)
+	return expires + "." + u.sign(shortCode, passwordHash, expires)
+}
+
+// Valid determines whether a token unlocks the link with the provided short code and password hash, and has not expired
+func (u Unlocker) Valid(token string, shortCode string, passwordHash string, now time.Time) bool {
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() >= unix {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(u.sign(shortCode, passwordHash, expires)))
+}
+
+func (u Unlocker) sign(shortCode string, passwordHash string, expires string) string {
+	mac := hmac.New(sha256.New, u.Key)
+	mac.Write([]byte(shortCode + "\n" + passwordHash + "\n" + expires))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
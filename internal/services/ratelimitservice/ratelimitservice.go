@@ -0,0 +1,109 @@
+package ratelimitservice
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval determines how often idle buckets are discarded
+const sweepInterval = time.Minute
+
+// Limiter represents a set of token buckets, one per client key, that share the same rate and burst
+type Limiter struct {
+	rate  float64
+	burst int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+// bucket represents a single client's available tokens
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Result represents the outcome of taking a token from a client's bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// New returns a new Limiter that refills perMinute tokens each minute, holding no more than burst at once
+func New(perMinute int, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   burst,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the bucket identified by key, reporting whether the request may proceed
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), updatedAt: now}
+		l.buckets[key] = b
+	}
+
+	b.refill(now, l.rate, l.burst)
+
+	result := Result{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.wait(1 - b.tokens)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = l.wait(float64(l.burst) - b.tokens)
+
+	return result
+}
+
+// wait returns how long it takes to accrue the provided number of tokens
+func (l *Limiter) wait(tokens float64) time.Duration {
+	if l.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep discards buckets that have refilled completely, as they are indistinguishable from new buckets
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) < sweepInterval {
+		return
+	}
+
+	l.sweptAt = now
+	for key, b := range l.buckets {
+		b.refill(now, l.rate, l.burst)
+		if b.tokens >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (b *bucket) refill(now time.Time, rate float64, burst int) {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+}
@@ -0,0 +1,79 @@
+package ratelimitservice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItAllowsABurstThenLimits(t *testing.T) {
+	l := New(60, 3)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for i := 3; i > 0; i-- {
+		res := l.Allow("client1")
+		if res.Allowed != true || res.Remaining != i-1 || res.Limit != 3 {
+			t.Errorf("Expected request to be allowed with %d remaining, instead received '%+v'", i-1, res)
+		}
+	}
+
+	res := l.Allow("client1")
+	if res.Allowed != false {
+		t.Errorf("Expected request to be limited, instead received '%+v'", res)
+	}
+
+	if res.RetryAfter != time.Second {
+		t.Errorf("Expected retry after %s, instead received %s", time.Second, res.RetryAfter)
+	}
+
+	if res.Reset != 3*time.Second {
+		t.Errorf("Expected reset after %s, instead received %s", 3*time.Second, res.Reset)
+	}
+
+	// other clients have their own bucket
+	if res := l.Allow("client2"); res.Allowed != true {
+		t.Errorf("Expected other client to be allowed, instead received '%+v'", res)
+	}
+}
+
+func TestItRefillsTokensOverTime(t *testing.T) {
+	l := New(60, 2)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Allow("client1")
+	l.Allow("client1")
+
+	if res := l.Allow("client1"); res.Allowed != false {
+		t.Errorf("Expected request to be limited, instead received '%+v'", res)
+	}
+
+	now = now.Add(time.Second)
+	if res := l.Allow("client1"); res.Allowed != true {
+		t.Errorf("Expected request to be allowed after refill, instead received '%+v'", res)
+	}
+
+	// never refills beyond burst
+	now = now.Add(time.Hour)
+	if res := l.Allow("client1"); res.Remaining != 1 {
+		t.Errorf("Expected %d remaining, instead received '%+v'", 1, res)
+	}
+}
+
+func TestItDiscardsIdleBuckets(t *testing.T) {
+	l := New(60, 2)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Allow("client1")
+	now = now.Add(2 * sweepInterval)
+	l.Allow("client2")
+
+	if _, ok := l.buckets["client1"]; ok {
+		t.Errorf("Expected idle bucket to be discarded")
+	}
+
+	if len(l.buckets) != 1 {
+		t.Errorf("Expected %d bucket, instead received %d", 1, len(l.buckets))
+	}
+}
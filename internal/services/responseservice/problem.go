@@ -0,0 +1,147 @@
+package responseservice
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Error codes are stable, machine-readable identifiers of the class of failure that an error response represents,
+// which clients may rely on where the wording of messages may change
+const (
+	CodeInvalidJSON       = "invalid_json"
+	CodeInvalidRequest    = "invalid_request"
+	CodeInvalidURL        = "invalid_url"
+	CodeUnauthorized      = "unauthorized"
+	CodeIncorrectPassword = "incorrect_password"
+	CodeForbidden         = "forbidden"
+	CodeBlockedDomain     = "blocked_domain"
+	CodeThreatDetected    = "threat_detected"
+	CodeQuotaExceeded     = "quota_exceeded"
+	CodeNotFound          = "not_found"
+	CodeMethodNotAllowed  = "method_not_allowed"
+	CodeConflict          = "conflict"
+	CodeGone              = "gone"
+	CodeRateLimited       = "rate_limited"
+	CodeLoopDetected      = "loop_detected"
+	CodeInternal          = "internal_error"
+	CodeUnavailable       = "unavailable"
+)
+
+// ProblemTypeBase prefixes the error code of a problem to form its `type` URI
+const ProblemTypeBase = "urn:http-url-shortener:error:"
+
+// FieldError represents a property of a request that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ProblemRenderer renders error payloads as RFC 7807 problem details
+type ProblemRenderer struct{}
+
+// ErrorRenderers are the renderers that error responses are negotiated between, which also offer problem details
+var ErrorRenderers = []Renderer{JSONRenderer{}, ProblemRenderer{}, HTMLRenderer{}, TextRenderer{}, XMLRenderer{}}
+
+// MediaTypes returns the media types that problem details are rendered as
+func (ProblemRenderer) MediaTypes() []string {
+	return []string{"application/problem+json"}
+}
+
+// ContentType returns the Content-Type of rendered problem details
+func (ProblemRenderer) ContentType() string {
+	return "application/problem+json"
+}
+
+// Render writes an error payload as problem details, with its message as the `detail` and the extension members
+// `code`, `errors` (for field-level validation failures) and `requestId`
+func (ProblemRenderer) Render(w io.Writer, code int, status string, data interface{}) error {
+	problem := map[string]interface{}{
+		"type":   "about:blank",
+		"title":  http.StatusText(code),
+		"status": code,
+	}
+
+	if m, ok := normalise(data).(map[string]interface{}); ok {
+		for k, v := range m {
+			problem[k] = v
+		}
+
+		if message, ok := problem["message"]; ok {
+			problem["detail"] = message
+			delete(problem, "message")
+		}
+
+		if errorCode, ok := problem["code"].(string); ok && errorCode != "" {
+			problem["type"] = ProblemTypeBase + errorCode
+		}
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(body)
+	return err
+}
+
+// codeForStatus returns the error code of a response that has not been given one, according to its status code
+func codeForStatus(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusUnavailableForLegalReasons:
+		return CodeBlockedDomain
+	case http.StatusLoopDetected:
+		return CodeLoopDetected
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+
+	return CodeInternal
+}
+
+// WithErrorCode returns a copy of an error response identified by the provided error code
+func (r JSONResponse) WithErrorCode(code string) JSONResponse {
+	return r.withErrorData("code", code)
+}
+
+// WithFieldErrors returns a copy of an error response that details the request properties that failed validation
+func (r JSONResponse) WithFieldErrors(errs ...FieldError) JSONResponse {
+	return r.withErrorData("errors", errs)
+}
+
+// withErrorData returns a copy of an error response with the provided property of its data set
+func (r JSONResponse) withErrorData(key string, value interface{}) JSONResponse {
+	data, ok := r.payload.Data.(map[string]interface{})
+	if !ok || r.payload.Status != "err" {
+		return r
+	}
+
+	withValue := map[string]interface{}{
+		key: value,
+	}
+	for k, v := range data {
+		if k != key {
+			withValue[k] = v
+		}
+	}
+
+	r.payload.Data = withValue
+	return r
+}
@@ -0,0 +1,359 @@
+package responseservice
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Renderer writes the payload of a response in a particular format
+type Renderer interface {
+	// MediaTypes are the media types, matched against the Accept header, that the renderer produces
+	MediaTypes() []string
+
+	// ContentType is the Content-Type header that the renderer's output is written with
+	ContentType() string
+
+	// Render writes the payload of a response with the provided status code
+	Render(w io.Writer, code int, status string, data interface{}) error
+}
+
+// JSONRenderer renders payloads as JSON, which is the default for clients that don't ask for another format
+type JSONRenderer struct{}
+
+// HTMLRenderer renders payloads as a minimal HTML page, for browsers
+type HTMLRenderer struct{}
+
+// TextRenderer renders payloads as plain text - e.g. just the short URL of a shortened link, for curl users
+type TextRenderer struct{}
+
+// XMLRenderer renders payloads as XML
+type XMLRenderer struct{}
+
+// Renderers are the renderers that responses are negotiated between, in order of preference when a client accepts several equally
+var Renderers = []Renderer{JSONRenderer{}, HTMLRenderer{}, TextRenderer{}, XMLRenderer{}}
+
+// Negotiate returns the renderer of those provided (or Renderers if none are) that best matches an Accept header,
+// falling back to the first renderer if the header is empty or accepts none of them
+func Negotiate(accept string, renderers ...Renderer) Renderer {
+	if len(renderers) == 0 {
+		renderers = Renderers
+	}
+
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return renderers[0]
+	}
+
+	best, bestQ := renderers[0], 0.0
+	for _, renderer := range renderers {
+		if q := quality(ranges, renderer.MediaTypes()); q > bestQ {
+			best, bestQ = renderer, q
+		}
+	}
+
+	return best
+}
+
+// mediaRange represents a single media range of an Accept header, and its quality
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses the media ranges of an Accept header, ignoring any parameters other than their quality
+func parseAccept(accept string) []mediaRange {
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	return ranges
+}
+
+// quality returns the highest quality with which any of the provided media types is accepted,
+// each media type being accepted with the quality of its most specific matching media range
+func quality(ranges []mediaRange, mediaTypes []string) float64 {
+	best := 0.0
+	for _, mediaType := range mediaTypes {
+		typ, _, _ := strings.Cut(mediaType, "/")
+
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := -1
+			switch r.mediaType {
+			case mediaType:
+				s = 2
+			case typ + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			}
+
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+
+		if q > best {
+			best = q
+		}
+	}
+
+	return best
+}
+
+// MediaTypes returns the media types that JSON is rendered as
+func (JSONRenderer) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+// ContentType returns the Content-Type of rendered JSON
+func (JSONRenderer) ContentType() string {
+	return "application/json"
+}
+
+// Render writes the payload as JSON
+func (JSONRenderer) Render(w io.Writer, code int, status string, data interface{}) error {
+	body, err := json.Marshal(payload{Status: status, Data: data})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(body)
+	return err
+}
+
+// MediaTypes returns the media types that HTML is rendered as
+func (HTMLRenderer) MediaTypes() []string {
+	return []string{"text/html", "application/xhtml+xml"}
+}
+
+// ContentType returns the Content-Type of rendered HTML
+func (HTMLRenderer) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta name="robots" content="noindex, nofollow">
+  <title>{{.Title}}</title>
+  <style>body { font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em; color: #222; }</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Message}}<p>{{.Message}}</p>
+{{end}}{{if .Fields}}<dl>
+{{range .Fields}}  <dt>{{.Name}}</dt><dd>{{.Value}}</dd>
+{{end}}</dl>
+{{end}}</body>
+</html>
+`))
+
+// Render writes the payload as an HTML page, headed by the status code's text and listing the payload's fields
+func (HTMLRenderer) Render(w io.Writer, code int, status string, data interface{}) error {
+	type field struct{ Name, Value string }
+
+	page := struct {
+		Title   string
+		Message string
+		Fields  []field
+	}{Title: http.StatusText(code)}
+
+	if m, ok := normalise(data).(map[string]interface{}); ok {
+		for _, name := range sortedKeys(m) {
+			if name == "message" && status == "err" {
+				page.Message = textOf(m[name])
+				continue
+			}
+
+			page.Fields = append(page.Fields, field{Name: name, Value: textOf(m[name])})
+		}
+	} else if data != nil {
+		page.Message = textOf(normalise(data))
+	}
+
+	return htmlPage.Execute(w, page)
+}
+
+// MediaTypes returns the media types that plain text is rendered as
+func (TextRenderer) MediaTypes() []string {
+	return []string{"text/plain"}
+}
+
+// ContentType returns the Content-Type of rendered plain text
+func (TextRenderer) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// Render writes the payload as plain text. A payload with a single field (such as an error's message, or a shortened
+// link's short URL) is written as just its value, otherwise each field is written on its own line as `name: value`
+func (TextRenderer) Render(w io.Writer, code int, status string, data interface{}) error {
+	value := normalise(data)
+
+	if m, ok := value.(map[string]interface{}); ok && status == "err" {
+		value = m["message"]
+	}
+
+	lines := []string{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for _, single := range v {
+				lines = append(lines, textOf(single))
+			}
+			break
+		}
+
+		for _, name := range sortedKeys(v) {
+			lines = append(lines, name+": "+textOf(v[name]))
+		}
+	case []interface{}:
+		for _, item := range v {
+			lines = append(lines, textOf(item))
+		}
+	case nil:
+	default:
+		lines = append(lines, textOf(v))
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// MediaTypes returns the media types that XML is rendered as
+func (XMLRenderer) MediaTypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+// ContentType returns the Content-Type of rendered XML
+func (XMLRenderer) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+// Render writes the payload as XML, with a `<response>` root element holding its `<status>` and `<data>`.
+// Objects' fields are written as elements of the same name, and arrays' values as repeated `<item>` elements
+func (XMLRenderer) Render(w io.Writer, code int, status string, data interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	buf := &strings.Builder{}
+	buf.WriteString("<response>")
+	writeXMLElement(buf, "status", status)
+	writeXMLElement(buf, "data", normalise(data))
+	buf.WriteString("</response>\n")
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func writeXMLElement(buf *strings.Builder, name string, value interface{}) {
+	// fall back to a generic element for names that aren't valid XML names
+	open, close := name, name
+	if !isXMLName(name) {
+		attr := &strings.Builder{}
+		xml.EscapeText(attr, []byte(name))
+		open, close = `entry key="`+attr.String()+`"`, "entry"
+	}
+
+	buf.WriteString("<" + open + ">")
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			writeXMLElement(buf, key, v[key])
+		}
+	case []interface{}:
+		for _, item := range v {
+			writeXMLElement(buf, "item", item)
+		}
+	case nil:
+	default:
+		xml.EscapeText(buf, []byte(textOf(v)))
+	}
+	buf.WriteString("</" + close + ">")
+}
+
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+
+	for i, c := range name {
+		letter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+		if !letter && (i == 0 || !((c >= '0' && c <= '9') || c == '-' || c == '.')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalise converts a payload's data to the generic maps, slices and values that it is written as in JSON,
+// so that renderers needn't handle every type that handlers respond with
+func normalise(data interface{}) interface{} {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Sprint(data)
+	}
+
+	var value interface{}
+	json.Unmarshal(encoded, &value)
+
+	return value
+}
+
+// textOf formats a normalised value as text, writing objects and arrays as compact JSON
+func textOf(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+
+	return fmt.Sprint(value)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -3,7 +3,9 @@ package responseservice
 import (
 	"encoding/json"
 	"fmt"
+	"http-url-shortener/internal/services/logservice"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 )
 
@@ -16,11 +18,32 @@ type payload struct {
 // JSONResponse represents status code and payload of a response
 type JSONResponse struct {
 	payload    payload
+	html       string
 	headers    map[string]string
 	statusCode int
 }
 
+// Write writes the response, rendering any payload as JSON
 func (r JSONResponse) Write(w http.ResponseWriter) JSONResponse {
+	return r.render(w, JSONRenderer{}, slog.Default())
+}
+
+// WriteNegotiated writes the response, rendering any payload in the format that best matches the request's Accept header.
+// Error payloads may additionally be rendered as problem details
+func (r JSONResponse) WriteNegotiated(w http.ResponseWriter, req *http.Request) JSONResponse {
+	if r.html == "" && r.payload != (payload{}) {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	renderers := Renderers
+	if r.payload.Status == "err" {
+		renderers = ErrorRenderers
+	}
+
+	return r.render(w, Negotiate(req.Header.Get("Accept"), renderers...), logservice.FromContext(req.Context()))
+}
+
+func (r JSONResponse) render(w http.ResponseWriter, renderer Renderer, logger *slog.Logger) JSONResponse {
 	p := r.payload
 	h := r.headers
 	s := r.statusCode
@@ -30,8 +53,10 @@ func (r JSONResponse) Write(w http.ResponseWriter) JSONResponse {
 		s = http.StatusInternalServerError
 	}
 
-	// set default content type header
-	w.Header().Set("Content-Type", "application/json")
+	// set content type header of the payload, leaving responses without a body (such as redirects) without one
+	if p != (payload{}) {
+		w.Header().Set("Content-Type", renderer.ContentType())
+	}
 
 	// set header overrides
 	for k, v := range h {
@@ -41,11 +66,17 @@ func (r JSONResponse) Write(w http.ResponseWriter) JSONResponse {
 	// set status code
 	w.WriteHeader(s)
 
-	// if payload is not blank, then write as body
+	// write HTML body in place of a payload
+	if r.html != "" {
+		fmt.Fprint(w, r.html)
+		return r
+	}
+
+	// if payload is not blank, then write as body. The status has already been sent, so failures can only be logged
 	if p != (payload{}) {
-		// parse response payload
-		body, _ := json.Marshal(p)
-		fmt.Fprintf(w, string(body))
+		if err := renderer.Render(w, s, p.Status, p.Data); err != nil {
+			logger.Error("Failed to render response", "contentType", renderer.ContentType(), "error", err)
+		}
 	}
 
 	return r
@@ -79,6 +110,14 @@ func NewEmptyResponse(code int, headers ...string) JSONResponse {
 	return r
 }
 
+// NewHTMLResponse returns a new JSONResponse with an HTML body in place of a payload
+func NewHTMLResponse(html string, code int, headers ...string) JSONResponse {
+	r := NewEmptyResponse(code, append([]string{"Content-Type", "text/html; charset=utf-8"}, headers...)...)
+	r.html = html
+
+	return r
+}
+
 // NewOkResponse returns a new JSONResponse representing a successful request
 func NewOkResponse(data interface{}) JSONResponse {
 	r := JSONResponse{
@@ -92,25 +131,52 @@ func NewOkResponse(data interface{}) JSONResponse {
 	return r
 }
 
-// NewErrResponse returns a new JSONResponse representing a request that generated an error
+// NewErrResponse returns a new JSONResponse representing a request that generated an error, identified by
+// the error code of its status code unless given a more specific one with WithErrorCode
 func NewErrResponse(message string, code ...int) JSONResponse {
-	r := JSONResponse{
-		payload: payload{
-			Status: "err",
-			Data: map[string]string{
-				"message": message,
-			},
-		},
-	}
+	r := JSONResponse{}
 
 	// set status code if provided to function call
 	if len(code) > 0 {
 		r.statusCode = code[0]
 	}
 
+	r.payload = payload{
+		Status: "err",
+		Data: map[string]interface{}{
+			"message": message,
+			"code":    codeForStatus(r.statusCode),
+		},
+	}
+
 	return r
 }
 
+// WithHeaders returns a copy of a response with the provided header pairs added
+// e.g. "Retry-After", "30", "X-RateLimit-Limit", "10"
+func (r JSONResponse) WithHeaders(headers ...string) JSONResponse {
+	withHeaders := map[string]string{}
+	for k, v := range r.headers {
+		withHeaders[k] = v
+	}
+
+	for k, v := range NewEmptyResponse(0, headers...).headers {
+		withHeaders[k] = v
+	}
+
+	r.headers = withHeaders
+	return r
+}
+
+// WithRequestID returns a copy of an error response that references the provided request ID
+func (r JSONResponse) WithRequestID(requestID string) JSONResponse {
+	if requestID == "" {
+		return r
+	}
+
+	return r.withErrorData("requestId", requestID)
+}
+
 // ParseJSON marshals a JSON payload into a map
 func ParseJSON(resp *http.Response) map[string]interface{} {
 	bytes, _ := ioutil.ReadAll(resp.Body)
@@ -1,8 +1,12 @@
 package responseservice
 
 import (
+	"bytes"
+	"encoding/xml"
+	"http-url-shortener/internal/services/logservice"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -73,7 +77,7 @@ func TestItReturnsANewErrResponse(t *testing.T) {
 		t.Errorf("Expected payload status of '%s', instead received '%s'", "err", response.payload.Status)
 	}
 
-	if response.payload.Data.(map[string]string)["message"] != "Feels badgateway man :(" {
+	if response.payload.Data.(map[string]interface{})["message"] != "Feels badgateway man :(" {
 		t.Errorf("Expected payload data of 'Feels badgateway man :(', instead received '%+v'", response.payload.Data)
 	}
 
@@ -82,6 +86,66 @@ func TestItReturnsANewErrResponse(t *testing.T) {
 	}
 }
 
+func TestItWritesAnHTMLResponse(t *testing.T) {
+	writer := httptest.NewRecorder()
+	NewHTMLResponse("<p>Hello</p>", http.StatusForbidden, "Cache-Control", "no-store").WithRequestID("abc123").Write(writer)
+
+	if writer.Code != http.StatusForbidden {
+		t.Errorf("Expected status code of %d, instead received %d", http.StatusForbidden, writer.Code)
+	}
+
+	if writer.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Expected HTML content type, instead received '%s'", writer.Header().Get("Content-Type"))
+	}
+
+	if writer.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected Cache-Control header of '%s', instead received '%s'", "no-store", writer.Header().Get("Cache-Control"))
+	}
+
+	if writer.Body.String() != "<p>Hello</p>" {
+		t.Errorf("Expected HTML body, instead received '%s'", writer.Body.String())
+	}
+}
+
+func TestItAddsARequestIDToAnErrResponse(t *testing.T) {
+	response := NewErrResponse("Feels badgateway man :(", http.StatusBadGateway).WithRequestID("abc123")
+
+	data := response.payload.Data.(map[string]interface{})
+
+	if data["requestId"] != "abc123" {
+		t.Errorf("Expected request ID of '%s', instead received '%s'", "abc123", data["requestId"])
+	}
+
+	if data["message"] != "Feels badgateway man :(" {
+		t.Errorf("Expected payload data of 'Feels badgateway man :(', instead received '%+v'", response.payload.Data)
+	}
+}
+
+func TestItAddsHeadersToAResponse(t *testing.T) {
+	original := NewEmptyResponse(http.StatusTooManyRequests, "Retry-After", "30")
+	response := original.WithHeaders("X-RateLimit-Limit", "10", "X-RateLimit-Remaining")
+
+	if len(response.headers) != 2 {
+		t.Errorf("Expected 2 headers, instead received '%+v'", response.headers)
+	}
+
+	if response.headers["Retry-After"] != "30" || response.headers["X-RateLimit-Limit"] != "10" {
+		t.Errorf("Expected existing and added headers, instead received '%+v'", response.headers)
+	}
+
+	if len(original.headers) != 1 {
+		t.Errorf("Expected original response to be unmodified, instead received '%+v'", original.headers)
+	}
+}
+
+func TestItDoesNotAddARequestIDToAnOkResponse(t *testing.T) {
+	response := NewOkResponse(map[string]string{"hello": "world"}).WithRequestID("abc123")
+
+	if _, ok := response.payload.Data.(map[string]string)["requestId"]; ok {
+		t.Errorf("Expected no request ID, instead received '%+v'", response.payload.Data)
+	}
+}
+
 func TestItSuccessfullyWritesAResponse(t *testing.T) {
 	response := JSONResponse{
 		payload: payload{
@@ -146,3 +210,216 @@ func TestItSuccessfullyWritesAResponse(t *testing.T) {
 		t.Errorf("Expected data payload of {\"hello\":\"world\"}, instead received '%+v'", payload["data"])
 	}
 }
+
+func TestItWritesAnEmptyResponseWithoutAContentType(t *testing.T) {
+	writer := httptest.NewRecorder()
+	NewEmptyResponse(http.StatusMovedPermanently, "Location", "http://bbc.co.uk").Write(writer)
+
+	if writer.Header().Get("Content-Type") != "" {
+		t.Errorf("Expected no content type, instead received '%s'", writer.Header().Get("Content-Type"))
+	}
+
+	if writer.Body.Len() != 0 {
+		t.Errorf("Expected empty body, instead received '%s'", writer.Body.String())
+	}
+}
+
+func TestItNegotiatesARendererFromTheAcceptHeader(t *testing.T) {
+	tests := map[string]Renderer{
+		"":                 JSONRenderer{},
+		"*/*":              JSONRenderer{},
+		"application/json": JSONRenderer{},
+		"text/html,application/xml;q=0.9,*/*;q=0.8": HTMLRenderer{},
+		"text/plain":               TextRenderer{},
+		"text/*":                   HTMLRenderer{},
+		"text/*;q=0.5, text/plain": TextRenderer{},
+		"application/xml, application/json;q=0.5": XMLRenderer{},
+		"image/png":                JSONRenderer{},
+		"text/html;q=0, */*;q=0.1": JSONRenderer{},
+	}
+
+	for accept, expected := range tests {
+		if renderer := Negotiate(accept); renderer != expected {
+			t.Errorf("Expected renderer '%T' for '%s', instead received '%T'", expected, accept, renderer)
+		}
+	}
+}
+
+func TestItWritesANegotiatedResponse(t *testing.T) {
+	tests := map[string][]string{
+		"text/plain":       {"text/plain; charset=utf-8", "http://localhost:8080/ABC1\n"},
+		"application/xml":  {"application/xml; charset=utf-8", xml.Header + "<response><status>ok</status><data><shortURL>http://localhost:8080/ABC1</shortURL></data></response>\n"},
+		"application/json": {"application/json", `{"status":"ok","data":{"shortURL":"http://localhost:8080/ABC1"}}`},
+	}
+
+	for accept, expected := range tests {
+		request := httptest.NewRequest("POST", "/api/shorten", nil)
+		request.Header.Set("Accept", accept)
+
+		writer := httptest.NewRecorder()
+		NewOkResponse(map[string]string{"shortURL": "http://localhost:8080/ABC1"}).WriteNegotiated(writer, request)
+
+		if writer.Header().Get("Content-Type") != expected[0] {
+			t.Errorf("Expected content type of '%s' for '%s', instead received '%s'", expected[0], accept, writer.Header().Get("Content-Type"))
+		}
+
+		if writer.Header().Get("Vary") != "Accept" {
+			t.Errorf("Expected Vary header of '%s', instead received '%s'", "Accept", writer.Header().Get("Vary"))
+		}
+
+		if writer.Body.String() != expected[1] {
+			t.Errorf("Expected body of '%s' for '%s', instead received '%s'", expected[1], accept, writer.Body.String())
+		}
+	}
+}
+
+func TestItLogsResponsesThatFailToRender(t *testing.T) {
+	var logs bytes.Buffer
+
+	request := httptest.NewRequest("GET", "/api/links", nil)
+	request = request.WithContext(logservice.WithLogger(request.Context(), logservice.New(&logs, "info")))
+
+	writer := httptest.NewRecorder()
+	NewOkResponse(map[string]interface{}{"links": make(chan int)}).WriteNegotiated(writer, request)
+
+	if !strings.Contains(logs.String(), "Failed to render response") {
+		t.Errorf("Expected render failure to be logged, instead received '%s'", logs.String())
+	}
+}
+
+func TestItRendersAnErrorAsPlainTextAndHTML(t *testing.T) {
+	response := NewErrResponse("Rate limit exceeded <soon>", http.StatusTooManyRequests).WithRequestID("abc123")
+
+	text := &strings.Builder{}
+	TextRenderer{}.Render(text, response.statusCode, response.payload.Status, response.payload.Data)
+
+	if text.String() != "Rate limit exceeded <soon>\n" {
+		t.Errorf("Expected the message as plain text, instead received '%s'", text.String())
+	}
+
+	html := &strings.Builder{}
+	HTMLRenderer{}.Render(html, response.statusCode, response.payload.Status, response.payload.Data)
+
+	expected := []string{
+		"<h1>Too Many Requests</h1>",
+		"<p>Rate limit exceeded &lt;soon&gt;</p>",
+		"<dt>requestId</dt><dd>abc123</dd>",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(html.String(), e) {
+			t.Errorf("Expected '%s' to be rendered, instead received '%s'", e, html.String())
+		}
+	}
+}
+
+func TestItRendersNestedDataAsXML(t *testing.T) {
+	data := map[string]interface{}{
+		"links": []map[string]interface{}{{"shortCode": "ABC1", "clicks": 3}},
+		"a b":   "x & y",
+	}
+
+	buf := &strings.Builder{}
+	XMLRenderer{}.Render(buf, http.StatusOK, "ok", data)
+
+	expected := `<data><entry key="a b">x &amp; y</entry><links><item><clicks>3</clicks><shortCode>ABC1</shortCode></item></links></data>`
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected '%s' to be rendered, instead received '%s'", expected, buf.String())
+	}
+}
+
+func TestItIdentifiesAnErrResponseByTheCodeOfItsStatus(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          CodeInvalidRequest,
+		http.StatusTooManyRequests:     CodeRateLimited,
+		http.StatusInternalServerError: CodeInternal,
+	}
+
+	for status, expected := range tests {
+		data := NewErrResponse("Oops", status).payload.Data.(map[string]interface{})
+		if data["code"] != expected {
+			t.Errorf("Expected code of '%s' for status %d, instead received '%v'", expected, status, data["code"])
+		}
+	}
+
+	if NewErrResponse("Oops").payload.Data.(map[string]interface{})["code"] != CodeInternal {
+		t.Errorf("Expected code of '%s' without a status", CodeInternal)
+	}
+}
+
+func TestItAddsAnErrorCodeAndFieldErrorsToAnErrResponse(t *testing.T) {
+	original := NewErrResponse("`url` is not a valid URL", http.StatusBadRequest)
+	response := original.
+		WithErrorCode(CodeInvalidURL).
+		WithFieldErrors(FieldError{Field: "url", Message: "`url` is not a valid URL"})
+
+	data := response.payload.Data.(map[string]interface{})
+	if data["code"] != CodeInvalidURL || data["message"] != "`url` is not a valid URL" {
+		t.Errorf("Expected code of '%s', instead received '%+v'", CodeInvalidURL, data)
+	}
+
+	if errs := data["errors"].([]FieldError); len(errs) != 1 || errs[0].Field != "url" {
+		t.Errorf("Expected a field error for 'url', instead received '%+v'", data["errors"])
+	}
+
+	if original.payload.Data.(map[string]interface{})["code"] != CodeInvalidRequest {
+		t.Errorf("Expected original response to be unmodified, instead received '%+v'", original.payload.Data)
+	}
+
+	if ok := NewOkResponse(map[string]string{"hello": "world"}).WithErrorCode(CodeInvalidURL); ok.payload.Data.(map[string]string)["code"] != "" {
+		t.Errorf("Expected no code on an ok response, instead received '%+v'", ok.payload.Data)
+	}
+}
+
+func TestItWritesAnErrResponseAsProblemDetails(t *testing.T) {
+	request := httptest.NewRequest("POST", "/api/shorten", nil)
+	request.Header.Set("Accept", "application/problem+json")
+
+	writer := httptest.NewRecorder()
+	NewErrResponse("Destination host `evil.com` is blocked", http.StatusForbidden).
+		WithErrorCode(CodeBlockedDomain).
+		WithFieldErrors(FieldError{Field: "url", Message: "Destination host `evil.com` is blocked"}).
+		WithRequestID("abc123").
+		WriteNegotiated(writer, request)
+
+	if writer.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("Expected problem details content type, instead received '%s'", writer.Header().Get("Content-Type"))
+	}
+
+	problem := ParseJSON(writer.Result())
+
+	expected := map[string]interface{}{
+		"type":      "urn:http-url-shortener:error:blocked_domain",
+		"title":     "Forbidden",
+		"status":    float64(http.StatusForbidden),
+		"detail":    "Destination host `evil.com` is blocked",
+		"code":      "blocked_domain",
+		"requestId": "abc123",
+	}
+
+	for k, v := range expected {
+		if problem[k] != v {
+			t.Errorf("Expected '%s' of '%v', instead received '%v'", k, v, problem[k])
+		}
+	}
+
+	if _, ok := problem["message"]; ok {
+		t.Errorf("Expected message to be written as detail only, instead received '%+v'", problem)
+	}
+
+	if errs := problem["errors"].([]interface{}); len(errs) != 1 || errs[0].(map[string]interface{})["field"] != "url" {
+		t.Errorf("Expected a field error for 'url', instead received '%+v'", problem["errors"])
+	}
+}
+
+func TestItOnlyWritesErrResponsesAsProblemDetails(t *testing.T) {
+	request := httptest.NewRequest("GET", "/api/links", nil)
+	request.Header.Set("Accept", "application/problem+json, application/json;q=0.5")
+
+	writer := httptest.NewRecorder()
+	NewOkResponse(map[string]string{"hello": "world"}).WriteNegotiated(writer, request)
+
+	if writer.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON content type, instead received '%s'", writer.Header().Get("Content-Type"))
+	}
+}
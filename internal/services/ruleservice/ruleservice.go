@@ -0,0 +1,164 @@
+package ruleservice
+
+import (
+	"fmt"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Platforms lists the operating systems that rules may match, as determined from user agents
+var Platforms = []string{"ios", "android", "windows", "macos", "chromeos", "linux"}
+
+// Visitor represents the properties of a request that rules are matched against
+type Visitor struct {
+	Platform string
+	Language string
+	Country  string
+	Time     time.Time
+}
+
+// Evaluate returns the first of the ordered rules that matches the visitor
+func Evaluate(rules []shortenedurl.Rule, v Visitor) (shortenedurl.Rule, bool) {
+	for _, rule := range rules {
+		if Matches(rule, v) {
+			return rule, true
+		}
+	}
+
+	return shortenedurl.Rule{}, false
+}
+
+// Matches determines whether the visitor meets every one of the rule's conditions
+func Matches(rule shortenedurl.Rule, v Visitor) bool {
+	if len(rule.Platforms) > 0 && !slices.Contains(rule.Platforms, v.Platform) {
+		return false
+	}
+
+	if len(rule.Languages) > 0 && !slices.ContainsFunc(rule.Languages, func(l string) bool {
+		return matchesLanguage(l, v.Language)
+	}) {
+		return false
+	}
+
+	if len(rule.Countries) > 0 && !slices.ContainsFunc(rule.Countries, func(c string) bool {
+		return strings.EqualFold(c, v.Country)
+	}) {
+		return false
+	}
+
+	return inWindow(rule.From, rule.Until, v.Time)
+}
+
+// Validate checks that a rule's conditions are well formed
+func Validate(rule shortenedurl.Rule) error {
+	for _, p := range rule.Platforms {
+		if !slices.Contains(Platforms, p) {
+			return fmt.Errorf("Unknown platform `%s`", p)
+		}
+	}
+
+	for _, c := range rule.Countries {
+		if len(c) != 2 {
+			return fmt.Errorf("Invalid country code `%s`", c)
+		}
+	}
+
+	for _, t := range []string{rule.From, rule.Until} {
+		if _, err := minuteOfDay(t); t != "" && err != nil {
+			return fmt.Errorf("Invalid time of day `%s`", t)
+		}
+	}
+
+	return nil
+}
+
+// Platform determines the operating system of a user agent, or returns an empty string if it is not recognised
+func Platform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return "ios"
+	case strings.Contains(userAgent, "Android"):
+		return "android"
+	case strings.Contains(userAgent, "Windows"):
+		return "windows"
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return "macos"
+	case strings.Contains(userAgent, "CrOS"):
+		return "chromeos"
+	case strings.Contains(userAgent, "Linux"):
+		return "linux"
+	}
+
+	return ""
+}
+
+// Language determines the most preferred language of an Accept-Language header, or returns an empty string if there is none
+func Language(acceptLanguage string) string {
+	preferred, preferredQ := "", 0.0
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		// earlier languages win ties, as they are listed in order of preference
+		if tag != "" && tag != "*" && q > preferredQ {
+			preferred, preferredQ = strings.ToLower(tag), q
+		}
+	}
+
+	return preferred
+}
+
+// matchesLanguage determines whether a language matches a rule's language, either exactly or as a subtag of it - e.g. `en-GB` matches `en`
+func matchesLanguage(ruleLanguage string, language string) bool {
+	ruleLanguage = strings.ToLower(ruleLanguage)
+	return language == ruleLanguage || strings.HasPrefix(language, ruleLanguage+"-")
+}
+
+// inWindow determines whether a time falls between the `HH:MM` UTC times of day, with empty bounds left open
+func inWindow(from string, until string, t time.Time) bool {
+	if from == "" && until == "" {
+		return true
+	}
+
+	t = t.UTC()
+	now := t.Hour()*60 + t.Minute()
+
+	start, err := minuteOfDay(from)
+	if err != nil {
+		start = 0
+	}
+
+	end, err := minuteOfDay(until)
+	if err != nil {
+		end = 24 * 60
+	}
+
+	// a window that starts later than it ends wraps past midnight
+	if start > end {
+		return now >= start || now < end
+	}
+
+	return now >= start && now < end
+}
+
+// minuteOfDay parses a `HH:MM` time of day as the number of minutes since midnight
+func minuteOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
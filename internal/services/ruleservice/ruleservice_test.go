@@ -0,0 +1,89 @@
+package ruleservice
+
+import (
+	"http-url-shortener/internal/entities/shortenedurl"
+	"testing"
+	"time"
+)
+
+func TestItDeterminesThePlatformOfAUserAgent(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15": "ios",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36":                 "android",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36":                "windows",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15":           "macos",
+		"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36":                 "chromeos",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0":      "linux",
+		"curl/8.4.0": "",
+	}
+
+	for userAgent, expected := range tests {
+		if platform := Platform(userAgent); platform != expected {
+			t.Errorf("Expected platform of '%s' to be '%s', instead received '%s'", userAgent, expected, platform)
+		}
+	}
+}
+
+func TestItDeterminesThePreferredLanguage(t *testing.T) {
+	tests := map[string]string{
+		"fr-CA,fr;q=0.9,en;q=0.8": "fr-ca",
+		"en;q=0.5, de":            "de",
+		"*;q=1, es;q=0.2":         "es",
+		"en;q=0":                  "",
+		"":                        "",
+	}
+
+	for header, expected := range tests {
+		if language := Language(header); language != expected {
+			t.Errorf("Expected language of '%s' to be '%s', instead received '%s'", header, expected, language)
+		}
+	}
+}
+
+func TestItEvaluatesTheFirstMatchingRule(t *testing.T) {
+	rules := []shortenedurl.Rule{
+		{URL: "https://apps.apple.com", Platforms: []string{"ios"}},
+		{URL: "https://play.google.com", Platforms: []string{"android"}},
+		{URL: "https://bbc.co.uk/french", Languages: []string{"fr"}, Countries: []string{"ca"}},
+		{URL: "https://bbc.co.uk/night", From: "22:00", Until: "06:00"},
+	}
+
+	noon := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	midnight := time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		visitor  Visitor
+		expected string
+	}{
+		{Visitor{Platform: "ios", Time: noon}, "https://apps.apple.com"},
+		{Visitor{Platform: "android", Language: "fr-ca", Country: "CA", Time: noon}, "https://play.google.com"},
+		{Visitor{Platform: "windows", Language: "fr-ca", Country: "CA", Time: noon}, "https://bbc.co.uk/french"},
+		{Visitor{Platform: "windows", Language: "fr-ca", Country: "FR", Time: noon}, ""},
+		{Visitor{Platform: "linux", Time: midnight}, "https://bbc.co.uk/night"},
+	}
+
+	for _, test := range tests {
+		rule, _ := Evaluate(rules, test.visitor)
+		if rule.URL != test.expected {
+			t.Errorf("Expected '%+v' to be redirected to '%s', instead received '%s'", test.visitor, test.expected, rule.URL)
+		}
+	}
+}
+
+func TestItValidatesRules(t *testing.T) {
+	tests := map[string]shortenedurl.Rule{
+		"Unknown platform `blackberry`": {Platforms: []string{"blackberry"}},
+		"Invalid country code `GBR`":    {Countries: []string{"GBR"}},
+		"Invalid time of day `25:00`":   {From: "25:00"},
+	}
+
+	for expected, rule := range tests {
+		if err := Validate(rule); err == nil || err.Error() != expected {
+			t.Errorf("Expected error '%s', instead received '%v'", expected, err)
+		}
+	}
+
+	if err := Validate(shortenedurl.Rule{Platforms: []string{"ios"}, From: "09:00", Until: "17:30"}); err != nil {
+		t.Errorf("Not expecting error, instead received '%s'", err.Error())
+	}
+}
@@ -0,0 +1,77 @@
+package shortcodeservice
+
+import (
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"strings"
+)
+
+// confusables maps characters that are easily mistaken for digits (e.g. when read from print) to those digits
+var confusables = strings.NewReplacer("O", "0", "I", "1", "L", "1")
+
+// Canonicaliser maps short codes to their canonical form, so that codes differing only by case or confusable
+// characters are treated as the same code. Its zero value leaves short codes unchanged
+type Canonicaliser struct {
+	// CaseInsensitive folds short codes to uppercase
+	CaseInsensitive bool
+
+	// Confusables maps `O` to `0`, and `I` and `L` to `1` (implying CaseInsensitive)
+	Confusables bool
+}
+
+// Canonicalise returns the canonical form of the provided short code
+func (c Canonicaliser) Canonicalise(shortCode string) string {
+	if c.CaseInsensitive || c.Confusables {
+		shortCode = strings.ToUpper(shortCode)
+	}
+
+	if c.Confusables {
+		shortCode = confusables.Replace(shortCode)
+	}
+
+	return shortCode
+}
+
+// Generate a new short code in canonical form
+func (c Canonicaliser) Generate() string {
+	return c.Canonicalise(Generate())
+}
+
+// Migrate renames every existing short code that is not in canonical form. Short codes whose canonical form is
+// already taken are left as they are, and returned as conflicts. Existing short codes are read once up front,
+// rather than per short code, so that starting up stays cheap however many links are stored
+func (c Canonicaliser) Migrate(repo repositoryinterface.RepositoryInterface) (int, []string, error) {
+	urls, err := repo.List("")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	existing := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		existing[u.GetShort()] = true
+	}
+
+	migrated := 0
+	conflicts := []string{}
+
+	for _, u := range urls {
+		canonical := c.Canonicalise(u.GetShort())
+		if canonical == u.GetShort() {
+			continue
+		}
+
+		if existing[canonical] {
+			conflicts = append(conflicts, u.GetShort())
+			continue
+		}
+
+		if _, err := repo.Rename(u.GetShort(), canonical); err != nil {
+			return migrated, conflicts, err
+		}
+
+		delete(existing, u.GetShort())
+		existing[canonical] = true
+		migrated++
+	}
+
+	return migrated, conflicts, nil
+}
@@ -2,6 +2,7 @@ package shortcodeservice
 
 import (
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -28,3 +29,23 @@ func Generate() string {
 
 	return generated
 }
+
+// reservedPaths cannot be claimed as short codes, as they are routed elsewhere
+var reservedPaths = []string{
+	"api",
+	"healthz",
+	"metrics",
+	"readyz",
+	"version",
+}
+
+// IsReserved determines whether the provided short code collides with a reserved path
+func IsReserved(shortCode string) bool {
+	for _, p := range reservedPaths {
+		if strings.EqualFold(shortCode, p) {
+			return true
+		}
+	}
+
+	return false
+}
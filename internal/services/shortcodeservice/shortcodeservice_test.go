@@ -1,6 +1,10 @@
 package shortcodeservice
 
 import (
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/shortenedurlfilesystemrepository"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 )
@@ -21,3 +25,92 @@ func TestItGeneratesAValidShortCode(t *testing.T) {
 		}
 	}
 }
+
+func TestItIdentifiesReservedShortCodes(t *testing.T) {
+	for _, code := range []string{"api", "healthz", "READYZ", "version"} {
+		if IsReserved(code) != true {
+			t.Errorf("Expected '%s' to be reserved", code)
+		}
+	}
+
+	if IsReserved("ABC1") != false {
+		t.Errorf("Expected '%s' not to be reserved", "ABC1")
+	}
+}
+
+func TestItCanonicalisesShortCodes(t *testing.T) {
+	tests := []struct {
+		c        Canonicaliser
+		code     string
+		expected string
+	}{
+		{Canonicaliser{}, "abO1", "abO1"},
+		{Canonicaliser{CaseInsensitive: true}, "abO1", "ABO1"},
+		{Canonicaliser{Confusables: true}, "lobi", "10B1"},
+	}
+
+	for _, test := range tests {
+		if canonical := test.c.Canonicalise(test.code); canonical != test.expected {
+			t.Errorf("Expected '%s' to canonicalise to '%s', instead received '%s'", test.code, test.expected, canonical)
+		}
+	}
+
+	c := Canonicaliser{Confusables: true}
+	if code := c.Generate(); code != c.Canonicalise(code) {
+		t.Errorf("Expected generated short code '%s' to be canonical", code)
+	}
+}
+
+func TestItMigratesShortCodesToCanonicalForm(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "migrate")
+	defer os.RemoveAll(dir)
+
+	repo := shortenedurlfilesystemrepository.New(dir)
+	repo.Create(shortenedurl.New("http://bbc.co.uk", "abc1"))
+	repo.Create(shortenedurl.New("http://wikipedia.org", "D0E2"))
+	repo.Create(shortenedurl.New("http://golang.org", "DOE2"))
+	repo.Create(shortenedurl.New("http://github.com", "FGH3"))
+
+	migrated, conflicts, err := Canonicaliser{Confusables: true}.Migrate(repo)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if migrated != 1 {
+		t.Errorf("Expected %d short code to be migrated, instead received %d", 1, migrated)
+	}
+
+	if len(conflicts) != 1 || conflicts[0] != "DOE2" {
+		t.Errorf("Expected conflict with '%s', instead received '%+v'", "DOE2", conflicts)
+	}
+
+	if u, err := repo.RetrieveByShortCode("ABC1"); err != nil || u.GetLong() != "http://bbc.co.uk" {
+		t.Errorf("Expected short code to be migrated, instead received '%+v' (%v)", u, err)
+	}
+}
+
+func TestItReportsConflictsBetweenShortCodesMigratedToTheSameCanonicalForm(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "migrate")
+	defer os.RemoveAll(dir)
+
+	repo := shortenedurlfilesystemrepository.New(dir)
+	repo.Create(shortenedurl.New("http://bbc.co.uk", "abc1"))
+	repo.Create(shortenedurl.New("http://wikipedia.org", "ABCL"))
+
+	migrated, conflicts, err := Canonicaliser{Confusables: true}.Migrate(repo)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	if migrated != 1 {
+		t.Errorf("Expected %d short code to be migrated, instead received %d", 1, migrated)
+	}
+
+	if len(conflicts) != 1 {
+		t.Errorf("Expected %d conflict, instead received '%+v'", 1, conflicts)
+	}
+
+	if _, err := repo.RetrieveByShortCode("ABC1"); err != nil {
+		t.Errorf("Expected short code to be migrated, instead received '%s'", err.Error())
+	}
+}
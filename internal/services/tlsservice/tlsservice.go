@@ -0,0 +1,123 @@
+package tlsservice
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateReloader holds a TLS certificate loaded from disk, which can be swapped out whilst serving
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// New loads the provided certificate and key files into a new CertificateReloader
+func New(certFile string, keyFile string) (*CertificateReloader, error) {
+	c := &CertificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Reload re-reads the certificate and key files, retaining the current certificate if they are invalid
+func (c *CertificateReloader) Reload() error {
+	// stat before loading, so files replaced whilst loading are picked up by the next check
+	modTime := c.latestModTime()
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+
+	return nil
+}
+
+// ReloadIfModified reloads the certificate only if either file has changed since it was last loaded
+func (c *CertificateReloader) ReloadIfModified() (bool, error) {
+	c.mu.RLock()
+	modTime := c.modTime
+	c.mu.RUnlock()
+
+	if !c.latestModTime().After(modTime) {
+		return false, nil
+	}
+
+	if err := c.Reload(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Watch polls the certificate files at the provided interval until stop is closed
+func (c *CertificateReloader) Watch(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := c.ReloadIfModified(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// GetCertificate returns the current certificate, and satisfies tls.Config.GetCertificate
+// so that each new handshake picks up the latest certificate without affecting open connections
+func (c *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.cert == nil {
+		return nil, errors.New("No certificate loaded")
+	}
+
+	return c.cert, nil
+}
+
+// TLSConfig returns a new tls.Config that serves the reloadable certificate
+func (c *CertificateReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+func (c *CertificateReloader) latestModTime() time.Time {
+	var latest time.Time
+
+	for _, p := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest
+}
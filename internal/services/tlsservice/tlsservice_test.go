@@ -0,0 +1,134 @@
+package tlsservice
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestItFailsToCreateAReloaderWhenFilesAreMissing(t *testing.T) {
+	_, err := New("/does/not/exist/cert.pem", "/does/not/exist/key.pem")
+
+	if err == nil {
+		t.Errorf("Expected error when certificate files are missing, instead received nil")
+	}
+}
+
+func TestItServesTheLoadedCertificate(t *testing.T) {
+	dir := getTestDir(t)
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeTestCertificate(t, dir, "first.example")
+
+	c, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	assertCertificateCommonName(t, c, "first.example")
+}
+
+func TestItReloadsTheCertificateWhenFilesChange(t *testing.T) {
+	dir := getTestDir(t)
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeTestCertificate(t, dir, "first.example")
+
+	c, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	reloaded, err := c.ReloadIfModified()
+	if err != nil || reloaded != false {
+		t.Errorf("Expected no reload when files are unchanged, instead received %t (%v)", reloaded, err)
+	}
+
+	// replace files and push modification time forward
+	writeTestCertificate(t, dir, "second.example")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+
+	reloaded, err = c.ReloadIfModified()
+	if err != nil || reloaded != true {
+		t.Errorf("Expected reload when files are changed, instead received %t (%v)", reloaded, err)
+	}
+
+	assertCertificateCommonName(t, c, "second.example")
+}
+
+func TestItRetainsTheCurrentCertificateWhenReloadFails(t *testing.T) {
+	dir := getTestDir(t)
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeTestCertificate(t, dir, "first.example")
+
+	c, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	ioutil.WriteFile(certFile, []byte("garbage"), 0644)
+
+	if c.Reload() == nil {
+		t.Errorf("Expected error when reloading an invalid certificate, instead received nil")
+	}
+
+	assertCertificateCommonName(t, c, "first.example")
+}
+
+func assertCertificateCommonName(t *testing.T, c *CertificateReloader, expected string) {
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	if leaf.Subject.CommonName != expected {
+		t.Errorf("Expected certificate common name of '%s', instead received '%s'", expected, leaf.Subject.CommonName)
+	}
+}
+
+func getTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tlsservice")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err.Error())
+	}
+
+	return dir
+}
+
+func writeTestCertificate(t *testing.T, dir string, commonName string) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %s", err.Error())
+	}
+
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	return certFile, keyFile
+}
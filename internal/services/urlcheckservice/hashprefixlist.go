@@ -0,0 +1,152 @@
+package urlcheckservice
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// HashPrefixList represents a URLChecker backed by a local threat list file of SHA-256 hash prefixes, in the manner of Safe Browsing.
+// Each line of the file holds a threat type and a hex encoded prefix (of 4 to 32 bytes) of the hash of a URL expression -
+// e.g. `phishing 1a2b3c4d` - and lines starting with `#` are ignored
+type HashPrefixList struct {
+	path string
+
+	mu       sync.RWMutex
+	prefixes map[string]string
+	lengths  map[int]bool
+}
+
+// NewHashPrefixList returns a new HashPrefixList loaded from the provided file
+func NewHashPrefixList(path string) (*HashPrefixList, error) {
+	l := &HashPrefixList{path: path}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Reload re-reads the threat list file, retaining the previous list if it cannot be read
+func (l *HashPrefixList) Reload() error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	prefixes := map[string]string{}
+	lengths := map[int]bool{}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("Malformed threat list entry on line %d", n)
+		}
+
+		prefix := strings.ToLower(fields[1])
+		if _, err := hex.DecodeString(prefix); err != nil || len(prefix) < 8 || len(prefix) > 64 {
+			return fmt.Errorf("Malformed hash prefix on line %d", n)
+		}
+
+		prefixes[prefix] = fields[0]
+		lengths[len(prefix)] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.prefixes = prefixes
+	l.lengths = lengths
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Check returns the threat type of the first listed hash prefix that matches one of the URL's expressions
+func (l *HashPrefixList) Check(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, expression := range Expressions(u) {
+		sum := sha256.Sum256([]byte(expression))
+		hash := hex.EncodeToString(sum[:])
+
+		for length := range l.lengths {
+			if threat, ok := l.prefixes[hash[:length]]; ok {
+				return threat, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// Expressions returns the host suffix and path prefix combinations of a URL that are looked up in a threat list -
+// e.g. `a.b.example.com/1/2.html?q` yields `a.b.example.com/1/2.html?q`, `b.example.com/1/`, `example.com/` etc.
+func Expressions(u *url.URL) []string {
+	host := strings.ToLower(u.Hostname())
+
+	hosts := []string{host}
+	if net.ParseIP(host) == nil {
+		// up to 4 further hosts, formed from the last 5 components by successively removing the leading component
+		components := strings.Split(host, ".")
+		for i := max(1, len(components)-5); i < len(components)-1; i++ {
+			hosts = append(hosts, strings.Join(components[i:], "."))
+		}
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	paths := []string{}
+	if u.RawQuery != "" {
+		paths = append(paths, path+"?"+u.RawQuery)
+	}
+	paths = append(paths, path)
+
+	// up to 4 path prefixes, formed from the root and successive path segments
+	prefix := "/"
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(segments) && i < 4; i++ {
+		if prefix != path {
+			paths = append(paths, prefix)
+		}
+
+		prefix += segments[i] + "/"
+	}
+
+	expressions := []string{}
+	seen := map[string]bool{}
+	for _, h := range hosts {
+		for _, p := range paths {
+			if e := h + p; !seen[e] {
+				seen[e] = true
+				expressions = append(expressions, e)
+			}
+		}
+	}
+
+	return expressions
+}
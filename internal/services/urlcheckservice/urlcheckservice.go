@@ -0,0 +1,88 @@
+package urlcheckservice
+
+import (
+	"errors"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/repositoryinterface"
+	"log/slog"
+)
+
+// URLChecker determines whether a destination URL is a known threat, such as malware or phishing
+type URLChecker interface {
+	// Check returns the type of threat that the URL has been flagged as, or an empty string if it is not flagged
+	Check(rawURL string) (string, error)
+}
+
+// Mock represents a URLChecker that flags URLs from a fixed map of URL to threat type, for use in tests
+type Mock struct {
+	Threats map[string]string
+	Err     error
+}
+
+// Check returns the threat type mapped to the provided URL
+func (m Mock) Check(rawURL string) (string, error) {
+	if m.Err != nil {
+		return "", m.Err
+	}
+
+	return m.Threats[rawURL], nil
+}
+
+// Rescan checks every existing Shortened URL, quarantining those newly flagged and releasing those no longer flagged.
+// Shortened URLs that can't be checked or changed are logged and skipped. It returns the number of Shortened URLs that changed
+func Rescan(repo repositoryinterface.RepositoryInterface, checker URLChecker, logger *slog.Logger) (int, error) {
+	urls, err := repo.List("")
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, u := range urls {
+		threat, err := checkDestinations(u, checker)
+		if err != nil {
+			logger.Error("Failed to check link", "shortCode", u.GetShort(), "error", err)
+			continue
+		}
+
+		if threat == u.GetThreat() {
+			continue
+		}
+
+		// change only the threat, so that the link's other properties aren't reverted if it was updated since being listed
+		_, err = repo.SetThreat(u.GetShort(), threat)
+		if errors.Is(err, repositoryinterface.ErrShortenedURLNotFound) {
+			// deleted since being listed
+			continue
+		}
+
+		if err != nil {
+			logger.Error("Failed to set threat of link", "shortCode", u.GetShort(), "threat", threat, "error", err)
+			continue
+		}
+
+		changed++
+	}
+
+	return changed, nil
+}
+
+// checkDestinations returns the threat that the first flagged of a Shortened URL's default, rule and variant destinations has been flagged as
+func checkDestinations(u shortenedurl.ShortenedURL, checker URLChecker) (string, error) {
+	destinations := []string{u.GetLong()}
+	for _, rule := range u.GetRules() {
+		destinations = append(destinations, rule.URL)
+	}
+
+	for _, variant := range u.GetVariants() {
+		destinations = append(destinations, variant.URL)
+	}
+
+	for _, destination := range destinations {
+		threat, err := checker.Check(destination)
+		if threat != "" || err != nil {
+			return threat, err
+		}
+	}
+
+	return "", nil
+}
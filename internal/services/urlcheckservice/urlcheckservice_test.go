@@ -0,0 +1,187 @@
+package urlcheckservice
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"http-url-shortener/internal/entities/shortenedurl"
+	"http-url-shortener/internal/repositories/shortenedurlfilesystemrepository"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestItGeneratesURLExpressions(t *testing.T) {
+	u, _ := url.Parse("http://a.b.example.com/1/2.html?q=1")
+
+	expected := []string{
+		"a.b.example.com/1/2.html?q=1",
+		"a.b.example.com/1/2.html",
+		"a.b.example.com/",
+		"a.b.example.com/1/",
+		"b.example.com/1/2.html?q=1",
+		"b.example.com/1/2.html",
+		"b.example.com/",
+		"b.example.com/1/",
+		"example.com/1/2.html?q=1",
+		"example.com/1/2.html",
+		"example.com/",
+		"example.com/1/",
+	}
+
+	if e := Expressions(u); !reflect.DeepEqual(e, expected) {
+		t.Errorf("Expected expressions '%+v', instead received '%+v'", expected, e)
+	}
+}
+
+func TestItChecksURLsAgainstAHashPrefixList(t *testing.T) {
+	list := getTestList(t, "# test list\n"+
+		"phishing "+hashPrefix("evil.example/", 4)+"\n"+
+		"malware "+hashPrefix("bad.example/downloads/", 32)+"\n",
+	)
+
+	tests := map[string]string{
+		"http://evil.example":                     "phishing",
+		"https://login.evil.example/account?id=1": "phishing",
+		"http://bad.example/downloads/virus.exe":  "malware",
+		"http://bad.example/about":                "",
+		"http://bbc.co.uk":                        "",
+	}
+
+	for u, expected := range tests {
+		threat, err := list.Check(u)
+		if err != nil {
+			t.Errorf("Not expecting error, instead received '%s'", err.Error())
+		}
+
+		if threat != expected {
+			t.Errorf("Expected '%s' to be flagged as '%s', instead received '%s'", u, expected, threat)
+		}
+	}
+}
+
+func TestItFailsToLoadAMalformedHashPrefixList(t *testing.T) {
+	f, _ := ioutil.TempFile("", "threats")
+	f.Write([]byte("phishing not-hex\n"))
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if _, err := NewHashPrefixList(f.Name()); err == nil || err.Error() != "Malformed hash prefix on line 1" {
+		t.Errorf("Expected error '%s', instead received '%v'", "Malformed hash prefix on line 1", err)
+	}
+}
+
+func TestItRescansExistingShortenedURLs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "rescan")
+	defer os.RemoveAll(dir)
+
+	repo := shortenedurlfilesystemrepository.New(dir)
+	repo.Create(shortenedurl.New("http://evil.example", "ABC1"))
+	repo.Create(shortenedurl.New("http://bbc.co.uk", "DEF2").WithThreat("malware"))
+	repo.Create(shortenedurl.New("http://wikipedia.org", "GHI3"))
+	repo.Create(shortenedurl.New("http://example.com", "JKL4").
+		WithRules([]shortenedurl.Rule{{URL: "http://evil.example/app", Platforms: []string{"ios"}}}))
+
+	checker := Mock{Threats: map[string]string{"http://evil.example": "phishing", "http://evil.example/app": "malware"}}
+
+	changed, err := Rescan(repo, checker, slog.Default())
+	if err != nil || changed != 3 {
+		t.Errorf("Expected %d changes, instead received %d (%v)", 3, changed, err)
+	}
+
+	if u, _ := repo.RetrieveByShortCode("JKL4"); u.GetThreat() != "malware" {
+		t.Errorf("Expected URL with a flagged rule destination to be quarantined, instead received '%s'", u.GetThreat())
+	}
+
+	if u, _ := repo.RetrieveByShortCode("ABC1"); u.GetThreat() != "phishing" {
+		t.Errorf("Expected newly flagged URL to be quarantined, instead received '%s'", u.GetThreat())
+	}
+
+	if u, _ := repo.RetrieveByShortCode("DEF2"); u.IsQuarantined() != false {
+		t.Errorf("Expected no longer flagged URL to be released")
+	}
+
+	if changed, err := Rescan(repo, Mock{Err: errors.New("Unavailable")}, slog.Default()); err != nil || changed != 0 {
+		t.Errorf("Expected checker errors to be skipped, instead received %d changes (%v)", changed, err)
+	}
+}
+
+// checkerFunc represents a URLChecker that calls a function, for use in tests
+type checkerFunc func(rawURL string) (string, error)
+
+func (f checkerFunc) Check(rawURL string) (string, error) {
+	return f(rawURL)
+}
+
+func TestItRescansShortenedURLsChangedWhileRescanning(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "rescan")
+	defer os.RemoveAll(dir)
+
+	repo := shortenedurlfilesystemrepository.New(dir)
+	repo.Create(shortenedurl.New("http://evil.example/deleted", "ABC1"))
+	repo.Create(shortenedurl.New("http://unavailable.example", "DEF2"))
+	repo.Create(shortenedurl.New("http://evil.example/updated", "GHI3"))
+	repo.Create(shortenedurl.New("http://evil.example", "JKL4"))
+
+	logs := &bytes.Buffer{}
+	checker := checkerFunc(func(rawURL string) (string, error) {
+		switch rawURL {
+		case "http://evil.example/deleted":
+			// deleted before its threat is set
+			repo.Delete("ABC1")
+		case "http://unavailable.example":
+			return "", errors.New("Unavailable")
+		case "http://evil.example/updated":
+			// updated before its threat is set
+			u, _ := repo.RetrieveByShortCode("GHI3")
+			repo.Update(u.WithTitle("Updated"))
+		}
+
+		return "phishing", nil
+	})
+
+	changed, err := Rescan(repo, checker, slog.New(slog.NewTextHandler(logs, nil)))
+	if err != nil || changed != 2 {
+		t.Errorf("Expected %d changes, instead received %d (%v)", 2, changed, err)
+	}
+
+	if u, _ := repo.RetrieveByShortCode("GHI3"); u.GetThreat() != "phishing" || u.GetTitle() != "Updated" {
+		t.Errorf("Expected updated URL to be quarantined and keep its update, instead received '%+v'", u)
+	}
+
+	if u, _ := repo.RetrieveByShortCode("JKL4"); u.GetThreat() != "phishing" {
+		t.Errorf("Expected URL after a failed check to be quarantined, instead received '%s'", u.GetThreat())
+	}
+
+	if !strings.Contains(logs.String(), "shortCode=DEF2") {
+		t.Errorf("Expected the failed check to be logged, instead received '%s'", logs.String())
+	}
+}
+
+func getTestList(t *testing.T, contents string) *HashPrefixList {
+	f, err := ioutil.TempFile("", "threats")
+	if err != nil {
+		t.Fatalf("Unable to create temp file: %s", err.Error())
+	}
+
+	f.Write([]byte(contents))
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	list, err := NewHashPrefixList(f.Name())
+	if err != nil {
+		t.Fatalf("Not expecting error, instead received '%s'", err.Error())
+	}
+
+	return list
+}
+
+func hashPrefix(expression string, length int) string {
+	sum := sha256.Sum256([]byte(expression))
+	return hex.EncodeToString(sum[:length])
+}
@@ -0,0 +1,118 @@
+package urlpolicyservice
+
+import (
+	"errors"
+	"strings"
+)
+
+// punycode parameters, as per RFC 3492
+const (
+	base        = 36
+	tMin        = 1
+	tMax        = 26
+	skew        = 38
+	damp        = 700
+	initialBias = 72
+	initialN    = 128
+)
+
+// encodePunycode encodes a unicode label as punycode, without the `xn--` prefix
+func encodePunycode(label string) (string, error) {
+	runes := []rune(label)
+	output := strings.Builder{}
+
+	// copy basic code points as-is
+	for _, r := range runes {
+		if r < 0x80 {
+			output.WriteRune(r)
+		}
+	}
+
+	basic := output.Len()
+	handled := basic
+	if basic > 0 {
+		output.WriteByte('-')
+	}
+
+	n, delta, bias := rune(initialN), 0, initialBias
+
+	for handled < len(runes) {
+		// find the next smallest code point yet to be handled
+		m := rune(0x10FFFF)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		if int(m-n) > (1<<31-1-delta)/(handled+1) {
+			return "", errors.New("Punycode overflow")
+		}
+
+		delta += int(m-n) * (handled + 1)
+		n = m
+
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := base; ; k += base {
+				t := k - bias
+				if t < tMin {
+					t = tMin
+				} else if t > tMax {
+					t = tMax
+				}
+
+				if q < t {
+					break
+				}
+
+				output.WriteByte(punycodeDigit(t + (q-t)%(base-t)))
+				q = (q - t) / (base - t)
+			}
+
+			output.WriteByte(punycodeDigit(q))
+			bias = adaptBias(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+
+		delta++
+		n++
+	}
+
+	return output.String(), nil
+}
+
+func adaptBias(delta int, numPoints int, first bool) int {
+	if first {
+		delta /= damp
+	} else {
+		delta /= 2
+	}
+
+	delta += delta / numPoints
+
+	k := 0
+	for delta > ((base-tMin)*tMax)/2 {
+		delta /= base - tMin
+		k += base
+	}
+
+	return k + (base-tMin+1)*delta/(delta+skew)
+}
+
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+
+	return byte('0' + d - 26)
+}
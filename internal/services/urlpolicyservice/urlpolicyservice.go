@@ -0,0 +1,182 @@
+package urlpolicyservice
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DefaultMaxLength is the maximum length of a destination URL if the Policy does not specify one
+const DefaultMaxLength = 2048
+
+// DefaultSchemes are the schemes permitted if the Policy does not specify any
+var DefaultSchemes = []string{"http", "https"}
+
+// defaultPorts are omitted from normalised URLs
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// trackingParams are query parameters removed when stripping tracking parameters, in addition to any `utm_` parameter
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+}
+
+// Policy represents the rules that destination URLs must satisfy. Its zero value permits http(s) URLs of up to DefaultMaxLength
+type Policy struct {
+	AllowedSchemes      []string
+	MaxLength           int
+	StripTrackingParams bool
+}
+
+// Normalise validates the provided URL against the Policy, returning its canonical form so that equivalent URLs are deduplicated
+func (p Policy) Normalise(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	maxLength := p.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+
+	if len(raw) > maxLength {
+		return "", fmt.Errorf("`url` exceeds maximum length of %d", maxLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Opaque != "" || u.Host == "" {
+		return "", errors.New("`url` is not a valid URL")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !p.allowsScheme(u.Scheme) {
+		return "", fmt.Errorf("`url` scheme `%s` is not allowed", u.Scheme)
+	}
+
+	if u.User != nil {
+		return "", errors.New("`url` must not contain credentials")
+	}
+
+	host, err := normaliseHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	// omit the scheme's default port
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	// treat a bare trailing slash as equivalent to no path
+	if u.Path == "/" {
+		u.Path = ""
+		u.RawPath = ""
+	}
+
+	if p.StripTrackingParams {
+		u.RawQuery = stripTrackingParams(u.RawQuery)
+	}
+
+	return u.String(), nil
+}
+
+func (p Policy) allowsScheme(scheme string) bool {
+	allowed := p.AllowedSchemes
+	if len(allowed) == 0 {
+		allowed = DefaultSchemes
+	}
+
+	for _, s := range allowed {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normaliseHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || len(host) > 253 {
+		return "", errors.New("`url` host is not valid")
+	}
+
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		ascii, err := toASCII(label)
+		if err != nil || !isValidLabel(ascii) {
+			return "", errors.New("`url` host is not valid")
+		}
+
+		labels[i] = ascii
+	}
+
+	return strings.Join(labels, "."), nil
+}
+
+// toASCII converts an internationalised label to its punycode form
+func toASCII(label string) (string, error) {
+	for _, r := range label {
+		if r >= 0x80 {
+			encoded, err := encodePunycode(label)
+			if err != nil {
+				return "", err
+			}
+
+			return "xn--" + encoded, nil
+		}
+	}
+
+	return label, nil
+}
+
+func isValidLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+
+	for _, c := range label {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stripTrackingParams(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	// filter the raw pairs, to preserve the order and encoding of those that remain
+	kept := []string{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, _ := url.QueryUnescape(strings.SplitN(pair, "=", 2)[0])
+		key = strings.ToLower(key)
+
+		if strings.HasPrefix(key, "utm_") || trackingParams[key] {
+			continue
+		}
+
+		kept = append(kept, pair)
+	}
+
+	return strings.Join(kept, "&")
+}
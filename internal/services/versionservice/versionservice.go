@@ -0,0 +1,8 @@
+package versionservice
+
+// Version and Commit are populated at build time, e.g.
+// go build -ldflags "-X http-url-shortener/internal/services/versionservice.Commit=$(git rev-parse --short HEAD)"
+var (
+	Version = "1.0.0"
+	Commit  = "unknown"
+)